@@ -4,13 +4,16 @@ import (
 	"database/sql"
 	"log"
 
+	"DiscordBot/utils"
+
 	"github.com/bwmarrin/discordgo"
 	_ "github.com/lib/pq"
 )
 
 type Bot struct {
-	Db     *sql.DB
-	Client *discordgo.Session
+	Db      *sql.DB
+	Client  *discordgo.Session
+	Economy *utils.EconomyService
 }
 
 type ExchangeResponse struct {
@@ -35,7 +38,7 @@ func NewBot(token string, dbURL string) (*Bot, error) {
 		return nil, err
 	}
 
-	bot := &Bot{Db: db, Client: client}
+	bot := &Bot{Db: db, Client: client, Economy: utils.NewEconomyService(db)}
 	client.AddHandler(bot.guildCreate)
 
 	return bot, nil
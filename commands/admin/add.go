@@ -6,6 +6,7 @@ import (
 
 	"DiscordBot/bot"
 	"DiscordBot/commands"
+	"DiscordBot/commands/economy"
 	"DiscordBot/utils"
 	"github.com/bwmarrin/discordgo"
 )
@@ -42,7 +43,7 @@ func Add(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []st
 	}
 
 	// Extract the amount
-	amount := 0
+	amount := int64(0)
 	fmt.Sscanf(args[2], "%d", &amount)
 
 	// Validate amount
@@ -51,13 +52,20 @@ func Add(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []st
 		return
 	}
 
-	// Add coins to the recipient
-	_, err = b.Db.Exec("UPDATE users SET balance = balance + $1 WHERE guild_id = $2 AND user_id = $3", amount, m.GuildID, recipientID)
+	// Add coins to the recipient through the ledger
+	newBalance, err := b.Economy.Adjust(utils.BalanceChange{
+		GuildID: m.GuildID,
+		UserID:  recipientID,
+		Amount:  amount,
+		Reason:  utils.ReasonAdminAdd,
+		ActorID: m.Author.ID,
+	})
 	if err != nil {
 		log.Printf("Error adding coins to user: %v", err)
 		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
 		return
 	}
 
-	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Added %d coins to <@%s>", amount, recipientID))
+	settings := economy.GuildSettings(b, m.GuildID)
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Added %s to <@%s>. New balance: %s", settings.Format(amount), recipientID, settings.Format(newBalance)))
 }
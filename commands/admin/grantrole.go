@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/commands/economy"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	commands.RegisterCommand("grantrole", GrantRole)
+}
+
+// GrantRole credits every member of a role with the same amount in one transaction
+func GrantRole(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	if len(args) < 3 {
+		s.ChannelMessageSend(m.ChannelID, "Usage: .grantrole <amount> <role name or mention>")
+		return
+	}
+
+	// Check if the user has administrator permissions
+	hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking admin status: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if !hasAdmin {
+		s.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command.")
+		return
+	}
+
+	amount := int64(0)
+	fmt.Sscanf(args[1], "%d", &amount)
+	if amount <= 0 {
+		s.ChannelMessageSend(m.ChannelID, "Amount must be greater than 0.")
+		return
+	}
+
+	roleInput := strings.Join(args[2:], " ")
+	role, err := utils.FindRole(s, m.GuildID, roleInput)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Role '%s' not found.", roleInput))
+		return
+	}
+
+	// Fetch members in the guild
+	holders, err := utils.RoleHolders(s, m.GuildID)
+	if err != nil {
+		log.Printf("Error fetching members: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while fetching members.")
+		return
+	}
+
+	userIDs := holders[role.ID]
+	if len(userIDs) == 0 {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("No members have the role '%s'.", role.Name))
+		return
+	}
+
+	err = b.Economy.Grant(m.GuildID, userIDs, amount, utils.ReasonAdminGrant, m.Author.ID)
+	if err != nil {
+		log.Printf("Error granting coins to role %s: %v", role.ID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	settings := economy.GuildSettings(b, m.GuildID)
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Granted %s to %d members of '%s'.", settings.Format(amount), len(userIDs), role.Name))
+}
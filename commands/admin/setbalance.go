@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"fmt"
+	"log"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/commands/economy"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	commands.RegisterCommand("setbalance", SetBalance, "setbal")
+}
+
+// SetBalance sets a member's balance to an exact amount, recording the difference in the ledger
+func SetBalance(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	if len(args) < 3 {
+		s.ChannelMessageSend(m.ChannelID, "Usage: .setbalance <@user> <amount>")
+		return
+	}
+
+	// Check if the user has administrator permissions
+	hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking admin status: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if !hasAdmin {
+		s.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command.")
+		return
+	}
+
+	// Extract and validate the target mention
+	targetID, err := utils.ExtractUserID(args[1])
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Invalid mention. Please use a proper mention (e.g., @username).")
+		return
+	}
+
+	amount := int64(-1)
+	_, err = fmt.Sscanf(args[2], "%d", &amount)
+	if err != nil || amount < 0 {
+		s.ChannelMessageSend(m.ChannelID, "Amount must be 0 or more.")
+		return
+	}
+
+	before, _, err := b.Economy.Mutate(utils.BalanceChange{
+		GuildID: m.GuildID,
+		UserID:  targetID,
+		Reason:  utils.ReasonAdminSet,
+		ActorID: m.Author.ID,
+	}, func(balance int64) (int64, error) {
+		return amount - balance, nil
+	})
+	if err != nil {
+		log.Printf("Error setting balance: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	settings := economy.GuildSettings(b, m.GuildID)
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Set <@%s>'s balance to %s (was %s).", targetID, settings.Format(amount), settings.Format(before)))
+}
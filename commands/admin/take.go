@@ -1,15 +1,20 @@
 package admin
 
 import (
+	"errors"
 	"fmt"
 	"log"
 
 	"DiscordBot/bot"
 	"DiscordBot/commands"
+	"DiscordBot/commands/economy"
 	"DiscordBot/utils"
 	"github.com/bwmarrin/discordgo"
 )
 
+// errNothingToTake is returned when taking all from a member with an empty wallet
+var errNothingToTake = errors.New("nothing to take")
+
 func init() {
 	commands.RegisterCommand("take", Take)
 }
@@ -41,21 +46,9 @@ func Take(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []s
 		return
 	}
 
-	// Get the recipient's balance
-	var recipientBalance int
-	err = b.Db.QueryRow("SELECT balance FROM users WHERE guild_id = $1 AND user_id = $2", m.GuildID, recipientID).Scan(&recipientBalance)
-	if err != nil {
-		log.Printf("Error querying recipient balance: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
-		return
-	}
-
-	// Handle "all" case by setting amount to the recipient's total balance
-	amount := 0
-	if args[2] == "all" {
-		amount = recipientBalance
-	} else {
-		// Parse the amount
+	// Parse the amount up front; "all" is resolved against the locked balance
+	amount := int64(0)
+	if args[2] != "all" {
 		_, err = fmt.Sscanf(args[2], "%d", &amount)
 		if err != nil || amount <= 0 {
 			s.ChannelMessageSend(m.ChannelID, "Invalid amount. Usage: .take <@user> <amount|all>")
@@ -63,23 +56,42 @@ func Take(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []s
 		}
 	}
 
-	// Check if the recipient has enough coins (only needed for specific amounts, not "all")
-	if args[2] != "all" && recipientBalance < amount {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("User <@%s> only has %d coins, cannot take %d.", recipientID, recipientBalance, amount))
-		return
-	}
+	settings := economy.GuildSettings(b, m.GuildID)
 
-	// Remove coins from the recipient
-	_, err = b.Db.Exec("UPDATE users SET balance = balance - $1 WHERE guild_id = $2 AND user_id = $3", amount, m.GuildID, recipientID)
-	if err != nil {
+	before, _, err := b.Economy.Mutate(utils.BalanceChange{
+		GuildID: m.GuildID,
+		UserID:  recipientID,
+		Reason:  utils.ReasonAdminTake,
+		ActorID: m.Author.ID,
+	}, func(balance int64) (int64, error) {
+		// Handle "all" case by setting amount to the recipient's total balance
+		if args[2] == "all" {
+			amount = balance
+			if amount == 0 {
+				return 0, errNothingToTake
+			}
+		}
+		if balance < amount {
+			return 0, utils.ErrInsufficientFunds
+		}
+		return -amount, nil
+	})
+
+	if errors.Is(err, errNothingToTake) {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("User <@%s> has nothing to take.", recipientID))
+		return
+	} else if errors.Is(err, utils.ErrInsufficientFunds) {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("User <@%s> only has %s, cannot take %d.", recipientID, settings.Format(before), amount))
+		return
+	} else if err != nil {
 		log.Printf("Error removing coins from user: %v", err)
 		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
 		return
 	}
 
 	if args[2] == "all" {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Took all %d coins from <@%s>", amount, recipientID))
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Took all %s from <@%s>", settings.Format(amount), recipientID))
 	} else {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Took %d coins from <@%s>", amount, recipientID))
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Took %s from <@%s>", settings.Format(amount), recipientID))
 	}
-}
\ No newline at end of file
+}
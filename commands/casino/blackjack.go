@@ -0,0 +1,541 @@
+package casino
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/commands/economy"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Hands left unfinished this long are settled as a stand by BlackjackExpiryService
+const blackjackTimeout = 10 * time.Minute
+
+// Player actions, stored in casino_games.actions in the order they were taken
+const (
+	actionHit    = 'h'
+	actionStand  = 's'
+	actionDouble = 'd'
+)
+
+var errInvalidAction = errors.New("invalid blackjack action")
+
+func init() {
+	commands.RegisterCommand("blackjack", Blackjack, "bj")
+	commands.RegisterComponent("bj", BlackjackButton)
+}
+
+// blackjackHand is a single deck dealt from a shuffle drawn with the game's fair RNG.
+// The whole game is rebuilt from the seeds and the list of actions, so nothing
+// is kept in memory between button presses.
+type blackjackHand struct {
+	deck     []int
+	next     int
+	player   []int
+	dealer   []int
+	doubled  bool
+	finished bool
+}
+
+func newBlackjackHand(rng *utils.FairRNG) *blackjackHand {
+	deck := make([]int, 52)
+	for i := range deck {
+		deck[i] = i
+	}
+	// Fisher-Yates shuffle
+	for i := len(deck) - 1; i > 0; i-- {
+		j := rng.Intn(i + 1)
+		deck[i], deck[j] = deck[j], deck[i]
+	}
+
+	h := &blackjackHand{deck: deck}
+	h.player = append(h.player, h.draw())
+	h.dealer = append(h.dealer, h.draw())
+	h.player = append(h.player, h.draw())
+	h.dealer = append(h.dealer, h.draw())
+
+	// Naturals end the game immediately
+	if handValue(h.player) == 21 || handValue(h.dealer) == 21 {
+		h.finished = true
+	}
+	return h
+}
+
+// replayBlackjack rebuilds a game from its seeds and actions
+func replayBlackjack(round *fairRound, actions string) (*blackjackHand, error) {
+	h := newBlackjackHand(round.rng())
+	for _, action := range actions {
+		if err := h.apply(action); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+func (h *blackjackHand) draw() int {
+	card := h.deck[h.next]
+	h.next++
+	return card
+}
+
+func (h *blackjackHand) canDouble() bool {
+	return !h.finished && len(h.player) == 2
+}
+
+func (h *blackjackHand) apply(action rune) error {
+	if h.finished {
+		return errInvalidAction
+	}
+
+	switch action {
+	case actionHit:
+		h.player = append(h.player, h.draw())
+	case actionDouble:
+		if !h.canDouble() {
+			return errInvalidAction
+		}
+		h.doubled = true
+		h.player = append(h.player, h.draw())
+	case actionStand:
+	default:
+		return errInvalidAction
+	}
+
+	value := handValue(h.player)
+	if value > 21 {
+		h.finished = true
+		return nil
+	}
+	if action == actionStand || action == actionDouble || value == 21 {
+		// Dealer stands on all 17s
+		for handValue(h.dealer) < 17 {
+			h.dealer = append(h.dealer, h.draw())
+		}
+		h.finished = true
+	}
+	return nil
+}
+
+// payout returns what the player gets back for a finished game and a summary
+func (h *blackjackHand) payout(bet int64, edge float64) (int64, string) {
+	stake := bet
+	if h.doubled {
+		stake *= 2
+	}
+
+	player, dealer := handValue(h.player), handValue(h.dealer)
+	playerNatural := player == 21 && len(h.player) == 2 && !h.doubled
+	dealerNatural := dealer == 21 && len(h.dealer) == 2
+
+	switch {
+	case player > 21:
+		return 0, "Bust! You lose."
+	case playerNatural && dealerNatural:
+		return stake, "Both have blackjack. Push."
+	case playerNatural:
+		// Blackjack pays 3:2
+		return stake + applyEdge(float64(stake)*1.5, edge), "Blackjack! You win."
+	case dealerNatural:
+		return 0, "Dealer has blackjack. You lose."
+	case dealer > 21 || player > dealer:
+		return stake + applyEdge(float64(stake), edge), "You win!"
+	case player == dealer:
+		return stake, "Push."
+	default:
+		return 0, "Dealer wins."
+	}
+}
+
+// handValue counts aces as 11 unless that would bust
+func handValue(hand []int) int {
+	value, aces := 0, 0
+	for _, card := range hand {
+		rank := card%13 + 1
+		switch {
+		case rank == 1:
+			value += 11
+			aces++
+		case rank > 10:
+			value += 10
+		default:
+			value += rank
+		}
+	}
+	for value > 21 && aces > 0 {
+		value -= 10
+		aces--
+	}
+	return value
+}
+
+func formatCards(hand []int, hideHole bool) string {
+	ranks := []string{"A", "2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K"}
+	suits := []string{"♠", "♥", "♦", "♣"}
+
+	cards := make([]string, len(hand))
+	for i, card := range hand {
+		if hideHole && i == 1 {
+			cards[i] = "`??`"
+			continue
+		}
+		cards[i] = fmt.Sprintf("`%s%s`", ranks[card%13], suits[card/13])
+	}
+	return strings.Join(cards, " ")
+}
+
+// blackjackGame is a casino_games row for a blackjack round
+type blackjackGame struct {
+	ID       int64
+	UserID   string
+	Bet      int64
+	Round    *fairRound
+	Actions  string
+	EscrowID int64
+}
+
+func lockBlackjackGame(tx *sql.Tx, gameID int64) (*blackjackGame, error) {
+	game := &blackjackGame{ID: gameID, Round: &fairRound{}}
+	err := tx.QueryRow(`
+		SELECT user_id, bet, server_seed, client_seed, nonce, actions, escrow_id
+		FROM casino_games
+		WHERE game_id = $1 AND game = $2
+		FOR UPDATE
+	`, gameID, gameBlackjack).Scan(&game.UserID, &game.Bet, &game.Round.ServerSeed, &game.Round.ClientSeed,
+		&game.Round.Nonce, &game.Actions, &game.EscrowID)
+	return game, err
+}
+
+// settleBlackjack releases the escrow and pays the player for a finished hand
+func settleBlackjack(tx *sql.Tx, game *blackjackGame, hand *blackjackHand, edge float64) (int64, string, error) {
+	escrow, err := utils.ReleaseEscrow(tx, game.EscrowID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	payout, summary := hand.payout(game.Bet, edge)
+	if payout > 0 {
+		if _, err := utils.LockBalance(tx, escrow.GuildID, escrow.UserID); err != nil {
+			return 0, "", err
+		}
+		_, err = utils.ApplyChange(tx, utils.BalanceChange{
+			GuildID: escrow.GuildID, UserID: escrow.UserID, Amount: payout,
+			Reason: utils.ReasonCasinoPayout, ActorID: escrow.UserID,
+		})
+		if err != nil {
+			return 0, "", err
+		}
+	}
+	return payout, summary, finishGame(tx, game.ID, payout)
+}
+
+// blackjackMessage renders the table and, while the hand is live, its buttons
+func blackjackMessage(game *blackjackGame, hand *blackjackHand, currency *utils.EconomySettings, summary string) (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	stake := game.Bet
+	if hand.doubled {
+		stake *= 2
+	}
+
+	dealerValue := fmt.Sprintf("%d", handValue(hand.dealer))
+	if !hand.finished {
+		dealerValue = "?"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "🃏 Blackjack",
+		Description: fmt.Sprintf("<@%s> bet %s", game.UserID, currency.Format(stake)),
+		Color:       0x00ff00,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: fmt.Sprintf("Your hand (%d)", handValue(hand.player)), Value: formatCards(hand.player, false), Inline: true},
+			{Name: fmt.Sprintf("Dealer (%s)", dealerValue), Value: formatCards(hand.dealer, !hand.finished), Inline: true},
+		},
+		Footer: fairFooter(game.ID, game.Round),
+	}
+	if summary != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Result", Value: summary})
+	}
+
+	if hand.finished {
+		return embed, []discordgo.MessageComponent{}
+	}
+
+	id := strconv.FormatInt(game.ID, 10)
+	return embed, []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Hit", Style: discordgo.PrimaryButton, CustomID: commands.CustomID("bj", string(actionHit), id)},
+				discordgo.Button{Label: "Stand", Style: discordgo.SecondaryButton, CustomID: commands.CustomID("bj", string(actionStand), id)},
+				discordgo.Button{Label: "Double", Style: discordgo.SuccessButton, CustomID: commands.CustomID("bj", string(actionDouble), id), Disabled: !hand.canDouble()},
+			},
+		},
+	}
+}
+
+// Blackjack deals a hand. The bet is held in escrow until the hand is settled.
+func Blackjack(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	if len(args) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Usage: .blackjack <bet>")
+		return
+	}
+
+	bet, settings, ok := parseBet(b, s, m, gameBlackjack, args[1])
+	if !ok {
+		return
+	}
+
+	var game *blackjackGame
+	var hand *blackjackHand
+	var summary string
+	err := b.Economy.WithTx(func(tx *sql.Tx) error {
+		round, err := nextRound(tx, m.GuildID, m.Author.ID)
+		if err != nil {
+			return err
+		}
+
+		escrowID, err := utils.HoldEscrow(tx, m.GuildID, m.Author.ID, bet, gameBlackjack, utils.ReasonCasinoBet, blackjackTimeout)
+		if err != nil {
+			return err
+		}
+
+		gameID, err := recordGame(tx, m.GuildID, m.Author.ID, gameBlackjack, bet, round, escrowID)
+		if err != nil {
+			return err
+		}
+		game = &blackjackGame{ID: gameID, UserID: m.Author.ID, Bet: bet, Round: round, EscrowID: escrowID}
+
+		hand = newBlackjackHand(round.rng())
+		if hand.finished {
+			_, summary, err = settleBlackjack(tx, game, hand, settings.HouseEdge)
+		}
+		return err
+	})
+	if err != nil {
+		betError(b, s, m, err)
+		return
+	}
+
+	embed, components := blackjackMessage(game, hand, economy.GuildSettings(b, m.GuildID), summary)
+	_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: components,
+	})
+	if err != nil {
+		log.Printf("Error sending blackjack game: %v", err)
+	}
+}
+
+// BlackjackButton handles hit, stand and double. args are the action and the game ID.
+func BlackjackButton(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) < 2 || len(args[0]) != 1 {
+		return
+	}
+	action := rune(args[0][0])
+	gameID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return
+	}
+
+	settings, err := loadCasinoSettings(b, i.GuildID)
+	if err != nil {
+		log.Printf("Error loading casino settings for guild %s: %v", i.GuildID, err)
+		utils.RespondEphemeral(s, i, "An error occurred. Please try again.")
+		return
+	}
+
+	userID := utils.InteractionUserID(i)
+	var game *blackjackGame
+	var hand *blackjackHand
+	var summary string
+	var notOwner, refunded bool
+	err = b.Economy.WithTx(func(tx *sql.Tx) error {
+		var err error
+		game, err = lockBlackjackGame(tx, gameID)
+		if err != nil {
+			return err
+		}
+		if game.UserID != userID {
+			notOwner = true
+			return nil
+		}
+
+		escrow, err := utils.LockEscrow(tx, game.EscrowID)
+		if err != nil {
+			return err
+		}
+		if escrow.Status == utils.EscrowRefunded {
+			refunded = true
+			return nil
+		}
+		if escrow.Status != utils.EscrowHeld {
+			// Timed out and settled as a stand, show the final table
+			hand, err = replayBlackjack(game.Round, game.Actions)
+			if err == nil {
+				_, summary = hand.payout(game.Bet, settings.HouseEdge)
+				summary = "The hand timed out, so you stood. " + summary
+			}
+			return err
+		}
+
+		hand, err = replayBlackjack(game.Round, game.Actions)
+		if err != nil {
+			return err
+		}
+		if err := hand.apply(action); err != nil {
+			return err
+		}
+
+		if action == actionDouble {
+			if err := utils.AddToEscrow(tx, game.EscrowID, game.Bet, utils.ReasonCasinoBet); err != nil {
+				return err
+			}
+		}
+
+		game.Actions += string(action)
+		_, err = tx.Exec(`UPDATE casino_games SET actions = $1 WHERE game_id = $2`, game.Actions, game.ID)
+		if err != nil {
+			return err
+		}
+
+		if hand.finished {
+			_, summary, err = settleBlackjack(tx, game, hand, settings.HouseEdge)
+		}
+		return err
+	})
+
+	switch {
+	case notOwner:
+		utils.RespondEphemeral(s, i, "This isn't your game. Start your own with `.blackjack <bet>`.")
+		return
+	case refunded:
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    "This game expired and the bet was refunded.",
+				Components: []discordgo.MessageComponent{},
+			},
+		})
+		return
+	case errors.Is(err, errInvalidAction):
+		utils.RespondEphemeral(s, i, "You can't do that right now.")
+		return
+	case errors.Is(err, utils.ErrInsufficientFunds):
+		utils.RespondEphemeral(s, i, "You don't have enough to double down.")
+		return
+	case err != nil:
+		log.Printf("Error playing blackjack: %v", err)
+		utils.RespondEphemeral(s, i, "An error occurred. Please try again.")
+		return
+	}
+
+	embed, components := blackjackMessage(game, hand, economy.GuildSettings(b, i.GuildID), summary)
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+		},
+	})
+	if err != nil {
+		log.Printf("Error updating blackjack game: %v", err)
+	}
+}
+
+// BlackjackExpiryService settles hands the player walked away from as a stand, so a
+// bad hand can't be abandoned for a refund
+type BlackjackExpiryService struct {
+	b *bot.Bot
+}
+
+func NewBlackjackExpiryService(b *bot.Bot) *BlackjackExpiryService {
+	return &BlackjackExpiryService{b: b}
+}
+
+func (bs *BlackjackExpiryService) Start() {
+	log.Println("Starting blackjack expiry service...")
+	// Settle anything that expired while the bot was offline
+	bs.settleExpired()
+
+	ticker := time.NewTicker(1 * time.Minute) // Check every minute
+	defer ticker.Stop()
+
+	for range ticker.C {
+		bs.settleExpired()
+	}
+}
+
+func (bs *BlackjackExpiryService) settleExpired() {
+	rows, err := bs.b.Db.Query(`
+		SELECT g.game_id, g.guild_id
+		FROM casino_games g
+		JOIN escrows e ON e.escrow_id = g.escrow_id
+		WHERE g.game = $1 AND e.status = $2 AND e.expires_at <= NOW()
+	`, gameBlackjack, utils.EscrowHeld)
+	if err != nil {
+		log.Printf("Error querying expired blackjack games: %v", err)
+		return
+	}
+
+	expired := make(map[int64]string)
+	for rows.Next() {
+		var gameID int64
+		var guildID string
+		if err := rows.Scan(&gameID, &guildID); err != nil {
+			log.Printf("Error scanning blackjack game: %v", err)
+			continue
+		}
+		expired[gameID] = guildID
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating over blackjack games: %v", err)
+	}
+	rows.Close()
+
+	for gameID, guildID := range expired {
+		settings, err := loadCasinoSettings(bs.b, guildID)
+		if err != nil {
+			log.Printf("Error loading casino settings for guild %s: %v", guildID, err)
+			continue
+		}
+
+		err = bs.b.Economy.WithTx(func(tx *sql.Tx) error {
+			game, err := lockBlackjackGame(tx, gameID)
+			if err != nil {
+				return err
+			}
+			hand, err := replayBlackjack(game.Round, game.Actions)
+			if err != nil {
+				return err
+			}
+
+			if !hand.finished {
+				if err := hand.apply(actionStand); err != nil {
+					return err
+				}
+				game.Actions += string(actionStand)
+				_, err = tx.Exec(`UPDATE casino_games SET actions = $1 WHERE game_id = $2`, game.Actions, game.ID)
+				if err != nil {
+					return err
+				}
+			}
+
+			_, _, err = settleBlackjack(tx, game, hand, settings.HouseEdge)
+			return err
+		})
+		if err != nil && !errors.Is(err, utils.ErrEscrowClosed) {
+			// ErrEscrowClosed means the player finished the hand while we were looking
+			log.Printf("Error settling expired blackjack game %d: %v", gameID, err)
+		}
+	}
+}
@@ -0,0 +1,479 @@
+package casino
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/commands/economy"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+	"github.com/lib/pq"
+)
+
+// Games that admins can turn on and off
+const (
+	gameBlackjack = "blackjack"
+	gameSlots     = "slots"
+	gameDice      = "dice"
+	gameDuel      = "duel"
+)
+
+var casinoGames = []string{gameBlackjack, gameSlots, gameDice, gameDuel}
+
+const casinoUsage = "Usage:\n" +
+	"`.casino` - show the casino settings and your seeds\n" +
+	"`.casino seed [client seed]` - reveal your server seed and start a new pair\n" +
+	"`.casino verify <game id>` - show the seeds behind a game\n" +
+	"Admins: `.casino enable|disable <game>`, `.casino minbet|maxbet <amount>`, `.casino edge <percent>`"
+
+func init() {
+	commands.RegisterCommand("casino", Casino)
+}
+
+// casinoSettings holds a guild's casino configuration
+type casinoSettings struct {
+	MinBet    int64
+	MaxBet    int64
+	HouseEdge float64 // percent kept by the house
+	Disabled  map[string]bool
+}
+
+func loadCasinoSettings(b *bot.Bot, guildID string) (*casinoSettings, error) {
+	settings := &casinoSettings{MinBet: 10, MaxBet: 10000, HouseEdge: 2, Disabled: make(map[string]bool)}
+	var disabled []string
+	err := b.Db.QueryRow(`
+		SELECT COALESCE(casino_min_bet, $2), COALESCE(casino_max_bet, $3), COALESCE(casino_house_edge, $4),
+			COALESCE(casino_disabled_games, '{}')
+		FROM guilds
+		WHERE guild_id = $1
+	`, guildID, settings.MinBet, settings.MaxBet, settings.HouseEdge).Scan(
+		&settings.MinBet, &settings.MaxBet, &settings.HouseEdge, pq.Array(&disabled))
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	for _, game := range disabled {
+		settings.Disabled[game] = true
+	}
+	return settings, nil
+}
+
+// parseBet validates a bet against the game toggle and the guild's limits,
+// replying to the member when it is rejected
+func parseBet(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, game, input string) (int64, *casinoSettings, bool) {
+	settings, err := loadCasinoSettings(b, m.GuildID)
+	if err != nil {
+		log.Printf("Error loading casino settings for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return 0, nil, false
+	}
+
+	if settings.Disabled[game] {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s is disabled in this server.", strings.Title(game)))
+		return 0, nil, false
+	}
+
+	bet, err := strconv.ParseInt(input, 10, 64)
+	if err != nil || bet <= 0 {
+		s.ChannelMessageSend(m.ChannelID, "Invalid bet amount.")
+		return 0, nil, false
+	}
+
+	currency := economy.GuildSettings(b, m.GuildID)
+	if bet < settings.MinBet || bet > settings.MaxBet {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Bets must be between %s and %s.",
+			currency.Format(settings.MinBet), currency.Format(settings.MaxBet)))
+		return 0, nil, false
+	}
+
+	return bet, settings, true
+}
+
+// applyEdge scales a winning amount down by the house edge
+func applyEdge(amount float64, edge float64) int64 {
+	return int64(math.Floor(amount * (100 - edge) / 100))
+}
+
+// fairRound is the seed pair and nonce a single game is played with
+type fairRound struct {
+	ServerSeed string
+	ClientSeed string
+	Nonce      int64
+}
+
+func (r *fairRound) rng() *utils.FairRNG {
+	return utils.NewFairRNG(r.ServerSeed, r.ClientSeed, r.Nonce)
+}
+
+// nextRound takes the next nonce from the member's seed pair, creating the pair
+// on their first game
+func nextRound(tx *sql.Tx, guildID, userID string) (*fairRound, error) {
+	serverSeed, err := utils.NewServerSeed()
+	if err != nil {
+		return nil, err
+	}
+
+	round := &fairRound{}
+	err = tx.QueryRow(`
+		INSERT INTO casino_seeds (guild_id, user_id, server_seed, client_seed, nonce)
+		VALUES ($1, $2, $3, $2::text, 1)
+		ON CONFLICT (guild_id, user_id) DO UPDATE SET nonce = casino_seeds.nonce + 1
+		RETURNING server_seed, client_seed, nonce
+	`, guildID, userID, serverSeed).Scan(&round.ServerSeed, &round.ClientSeed, &round.Nonce)
+	return round, err
+}
+
+// recordGame stores the round so it can be verified once the seed is revealed
+func recordGame(tx *sql.Tx, guildID, userID, game string, bet int64, round *fairRound, escrowID int64) (int64, error) {
+	var escrow interface{}
+	if escrowID != 0 {
+		escrow = escrowID
+	}
+
+	var gameID int64
+	err := tx.QueryRow(`
+		INSERT INTO casino_games (guild_id, user_id, game, bet, server_seed, client_seed, nonce, escrow_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING game_id
+	`, guildID, userID, game, bet, round.ServerSeed, round.ClientSeed, round.Nonce, escrow).Scan(&gameID)
+	return gameID, err
+}
+
+// finishGame records the payout of a settled game
+func finishGame(tx *sql.Tx, gameID, payout int64) error {
+	_, err := tx.Exec(`
+		UPDATE casino_games SET payout = $1, finished_at = NOW() WHERE game_id = $2
+	`, payout, gameID)
+	return err
+}
+
+// fairFooter shows what a player needs to verify the game later
+func fairFooter(gameID int64, round *fairRound) *discordgo.MessageEmbedFooter {
+	return &discordgo.MessageEmbedFooter{
+		Text: fmt.Sprintf("Game #%d • Server seed hash %.16s… • Client seed %s • Nonce %d",
+			gameID, utils.HashSeed(round.ServerSeed), round.ClientSeed, round.Nonce),
+	}
+}
+
+// playInstant runs a game that settles immediately. The bet, the game record and
+// the payout share one transaction, so nothing is lost if the bot stops midway.
+func playInstant(b *bot.Bot, guildID, userID, game string, bet int64, play func(rng *utils.FairRNG) int64) (int64, int64, *fairRound, int64, error) {
+	var gameID, payout, newBalance int64
+	var round *fairRound
+	err := b.Economy.WithTx(func(tx *sql.Tx) error {
+		var err error
+		round, err = nextRound(tx, guildID, userID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := utils.LockBalance(tx, guildID, userID); err != nil {
+			return err
+		}
+		newBalance, err = utils.ApplyChange(tx, utils.BalanceChange{
+			GuildID: guildID, UserID: userID, Amount: -bet,
+			Reason: utils.ReasonCasinoBet, ActorID: userID,
+		})
+		if err != nil {
+			return err
+		}
+
+		gameID, err = recordGame(tx, guildID, userID, game, bet, round, 0)
+		if err != nil {
+			return err
+		}
+
+		payout = play(round.rng())
+		if payout > 0 {
+			newBalance, err = utils.ApplyChange(tx, utils.BalanceChange{
+				GuildID: guildID, UserID: userID, Amount: payout,
+				Reason: utils.ReasonCasinoPayout, ActorID: userID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return finishGame(tx, gameID, payout)
+	})
+	return gameID, payout, round, newBalance, err
+}
+
+// Casino shows the casino settings, manages seeds and lets admins configure games
+func Casino(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	if len(args) < 2 {
+		showCasino(b, s, m)
+		return
+	}
+
+	switch strings.ToLower(args[1]) {
+	case "seed":
+		rotateSeed(b, s, m, strings.Join(args[2:], " "))
+	case "verify":
+		if len(args) < 3 {
+			s.ChannelMessageSend(m.ChannelID, casinoUsage)
+			return
+		}
+		verifyGame(b, s, m, strings.TrimPrefix(args[2], "#"))
+	case "enable", "disable", "minbet", "maxbet", "edge":
+		configureCasino(b, s, m, args[1:])
+	default:
+		s.ChannelMessageSend(m.ChannelID, casinoUsage)
+	}
+}
+
+func showCasino(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate) {
+	settings, err := loadCasinoSettings(b, m.GuildID)
+	if err != nil {
+		log.Printf("Error loading casino settings for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+	currency := economy.GuildSettings(b, m.GuildID)
+
+	var games []string
+	for _, game := range casinoGames {
+		status := "✅"
+		if settings.Disabled[game] {
+			status = "❌"
+		}
+		games = append(games, fmt.Sprintf("%s %s", status, game))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: "Casino",
+		Color: 0x00ff00,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Games", Value: strings.Join(games, "\n"), Inline: true},
+			{Name: "Bets", Value: fmt.Sprintf("%s - %s", currency.Format(settings.MinBet), currency.Format(settings.MaxBet)), Inline: true},
+			{Name: "House Edge", Value: fmt.Sprintf("%g%%", settings.HouseEdge), Inline: true},
+		},
+	}
+
+	var serverSeed, clientSeed string
+	var nonce int64
+	err = b.Db.QueryRow(`
+		SELECT server_seed, client_seed, nonce FROM casino_seeds WHERE guild_id = $1 AND user_id = $2
+	`, m.GuildID, m.Author.ID).Scan(&serverSeed, &clientSeed, &nonce)
+	if err == nil {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: "Your Seeds",
+			Value: fmt.Sprintf("Server seed hash: `%s`\nClient seed: `%s`\nGames played: %d",
+				utils.HashSeed(serverSeed), clientSeed, nonce),
+		})
+	} else if err != sql.ErrNoRows {
+		log.Printf("Error querying casino seeds: %v", err)
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, embed)
+}
+
+// rotateSeed reveals the member's current server seed and starts a new pair
+func rotateSeed(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, clientSeed string) {
+	if clientSeed == "" {
+		clientSeed = m.Author.ID
+	}
+	if len(clientSeed) > 64 {
+		s.ChannelMessageSend(m.ChannelID, "Client seeds must be 64 characters or fewer.")
+		return
+	}
+
+	serverSeed, err := utils.NewServerSeed()
+	if err != nil {
+		log.Printf("Error generating server seed: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	var previousSeed sql.NullString
+	err = b.Economy.WithTx(func(tx *sql.Tx) error {
+		err := tx.QueryRow(`
+			SELECT server_seed FROM casino_seeds WHERE guild_id = $1 AND user_id = $2 FOR UPDATE
+		`, m.GuildID, m.Author.ID).Scan(&previousSeed)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO casino_seeds (guild_id, user_id, server_seed, client_seed, nonce)
+			VALUES ($1, $2, $3, $4, 0)
+			ON CONFLICT (guild_id, user_id) DO UPDATE SET
+				server_seed = EXCLUDED.server_seed,
+				client_seed = EXCLUDED.client_seed,
+				nonce = 0
+		`, m.GuildID, m.Author.ID, serverSeed, clientSeed)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error rotating casino seed: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	msg := fmt.Sprintf("New seed pair ready.\nServer seed hash: `%s`\nClient seed: `%s`", utils.HashSeed(serverSeed), clientSeed)
+	if previousSeed.Valid {
+		msg = fmt.Sprintf("Previous server seed: `%s`\nUse `.casino verify <game id>` to check your past games.\n\n%s", previousSeed.String, msg)
+	}
+	s.ChannelMessageSend(m.ChannelID, msg)
+}
+
+// verifyGame shows a game's seeds, revealing the server seed once it has been rotated
+func verifyGame(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, input string) {
+	gameID, err := strconv.ParseInt(input, 10, 64)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Invalid game ID.")
+		return
+	}
+
+	var userID, game, serverSeed, clientSeed string
+	var nonce, bet int64
+	var payout sql.NullInt64
+	var currentSeed sql.NullString
+	err = b.Db.QueryRow(`
+		SELECT g.user_id, g.game, g.bet, g.payout, g.server_seed, g.client_seed, g.nonce, cs.server_seed
+		FROM casino_games g
+		LEFT JOIN casino_seeds cs ON cs.guild_id = g.guild_id AND cs.user_id = g.user_id
+		WHERE g.game_id = $1 AND g.guild_id = $2
+	`, gameID, m.GuildID).Scan(&userID, &game, &bet, &payout, &serverSeed, &clientSeed, &nonce, &currentSeed)
+	if err == sql.ErrNoRows {
+		s.ChannelMessageSend(m.ChannelID, "Game not found.")
+		return
+	} else if err != nil {
+		log.Printf("Error querying casino game: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	revealed := "Hidden until the player runs `.casino seed`"
+	if !currentSeed.Valid || currentSeed.String != serverSeed {
+		revealed = fmt.Sprintf("`%s`", serverSeed)
+	}
+	result := "In progress"
+	if payout.Valid {
+		result = fmt.Sprintf("Bet %d, paid %d", bet, payout.Int64)
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("Game #%d - %s", gameID, game),
+		Description: "Each number is read from HMAC-SHA256(server seed, \"client seed:nonce:round\"), " +
+			"four bytes at a time, as a fraction of 2^32.",
+		Color: 0x00ff00,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Player", Value: fmt.Sprintf("<@%s>", userID), Inline: true},
+			{Name: "Result", Value: result, Inline: true},
+			{Name: "Server Seed Hash", Value: fmt.Sprintf("`%s`", utils.HashSeed(serverSeed))},
+			{Name: "Server Seed", Value: revealed},
+			{Name: "Client Seed", Value: fmt.Sprintf("`%s`", clientSeed), Inline: true},
+			{Name: "Nonce", Value: strconv.FormatInt(nonce, 10), Inline: true},
+		},
+	})
+}
+
+// configureCasino handles the admin subcommands
+func configureCasino(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Check if the user has administrator permissions
+	hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking admin status: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if !hasAdmin {
+		s.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command.")
+		return
+	}
+
+	if len(args) < 2 {
+		s.ChannelMessageSend(m.ChannelID, casinoUsage)
+		return
+	}
+
+	action, value := strings.ToLower(args[0]), strings.ToLower(args[1])
+	switch action {
+	case "enable", "disable":
+		valid := false
+		for _, game := range casinoGames {
+			if game == value {
+				valid = true
+			}
+		}
+		if !valid {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Unknown game. Use one of: %s.", strings.Join(casinoGames, ", ")))
+			return
+		}
+
+		if action == "disable" {
+			_, err = b.Db.Exec(`
+				UPDATE guilds
+				SET casino_disabled_games = array_append(array_remove(COALESCE(casino_disabled_games, '{}'), $1), $1)
+				WHERE guild_id = $2
+			`, value, m.GuildID)
+		} else {
+			_, err = b.Db.Exec(`
+				UPDATE guilds
+				SET casino_disabled_games = array_remove(COALESCE(casino_disabled_games, '{}'), $1)
+				WHERE guild_id = $2
+			`, value, m.GuildID)
+		}
+	case "minbet", "maxbet":
+		amount, parseErr := strconv.ParseInt(value, 10, 64)
+		if parseErr != nil || amount <= 0 {
+			s.ChannelMessageSend(m.ChannelID, "Amount must be greater than 0.")
+			return
+		}
+
+		settings, loadErr := loadCasinoSettings(b, m.GuildID)
+		if loadErr != nil {
+			log.Printf("Error loading casino settings for guild %s: %v", m.GuildID, loadErr)
+			s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+			return
+		}
+		if (action == "minbet" && amount > settings.MaxBet) || (action == "maxbet" && amount < settings.MinBet) {
+			s.ChannelMessageSend(m.ChannelID, "The minimum bet can't be higher than the maximum bet.")
+			return
+		}
+
+		column := "casino_min_bet"
+		if action == "maxbet" {
+			column = "casino_max_bet"
+		}
+		_, err = b.Db.Exec(fmt.Sprintf("UPDATE guilds SET %s = $1 WHERE guild_id = $2", column), amount, m.GuildID)
+	case "edge":
+		edge, parseErr := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if parseErr != nil || math.IsNaN(edge) || edge < 0 || edge > 20 {
+			s.ChannelMessageSend(m.ChannelID, "House edge must be between 0 and 20 percent.")
+			return
+		}
+		_, err = b.Db.Exec("UPDATE guilds SET casino_house_edge = $1 WHERE guild_id = $2", edge, m.GuildID)
+	}
+
+	if err != nil {
+		log.Printf("Error updating casino settings for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "Error updating the casino settings. Please try again later.")
+		return
+	}
+
+	showCasino(b, s, m)
+}
+
+// betError replies to the member for the errors shared by every game
+func betError(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, err error) {
+	if errors.Is(err, utils.ErrInsufficientFunds) {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Not enough %s.", economy.GuildSettings(b, m.GuildID).CurrencyName))
+		return
+	}
+	log.Printf("Error playing casino game: %v", err)
+	s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+}
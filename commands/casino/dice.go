@@ -0,0 +1,92 @@
+package casino
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/commands/economy"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const diceUsage = "Usage: .dice <bet> <over|under> <target>\nRolls 1-100. `over 50` wins on 51-100, `under 50` wins on 1-49."
+
+func init() {
+	commands.RegisterCommand("dice", Dice)
+}
+
+// Dice rolls 1-100 against a target chosen by the player. The payout is the
+// inverse of the win chance minus the house edge.
+func Dice(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	if len(args) < 4 {
+		s.ChannelMessageSend(m.ChannelID, diceUsage)
+		return
+	}
+
+	direction := strings.ToLower(args[2])
+	target, err := strconv.Atoi(args[3])
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, diceUsage)
+		return
+	}
+
+	// Number of winning rolls out of 100
+	var winning int
+	switch direction {
+	case "over":
+		winning = 100 - target
+	case "under":
+		winning = target - 1
+	default:
+		s.ChannelMessageSend(m.ChannelID, diceUsage)
+		return
+	}
+	if winning < 2 || winning > 98 {
+		s.ChannelMessageSend(m.ChannelID, "The target must give a win chance between 2% and 98%.")
+		return
+	}
+
+	bet, settings, ok := parseBet(b, s, m, gameDice, args[1])
+	if !ok {
+		return
+	}
+
+	multiplier := 100 / float64(winning)
+	var roll int
+	gameID, payout, round, newBalance, err := playInstant(b, m.GuildID, m.Author.ID, gameDice, bet, func(rng *utils.FairRNG) int64 {
+		roll = rng.Intn(100) + 1
+		won := (direction == "over" && roll > target) || (direction == "under" && roll < target)
+		if !won {
+			return 0
+		}
+		return applyEdge(float64(bet)*multiplier, settings.HouseEdge)
+	})
+	if err != nil {
+		betError(b, s, m, err)
+		return
+	}
+
+	currency := economy.GuildSettings(b, m.GuildID)
+	result := fmt.Sprintf("You lost %s.", currency.Format(bet))
+	color := 0xff0000
+	if payout > 0 {
+		result = fmt.Sprintf("You won %s!", currency.Format(payout))
+		color = 0x00ff00
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+		Title: "🎲 Dice",
+		Description: fmt.Sprintf("Rolled **%d** (needed %s %d, %d%% chance, x%.2f)\n\n%s\nBalance: %s",
+			roll, direction, target, winning, multiplier*(100-settings.HouseEdge)/100, result, currency.Format(newBalance)),
+		Color:  color,
+		Footer: fairFooter(gameID, round),
+	})
+}
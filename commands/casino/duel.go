@@ -0,0 +1,371 @@
+package casino
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/commands/economy"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Challenges that aren't answered in time are refunded by the escrow service
+const duelTimeout = 2 * time.Minute
+
+// How a duel is decided
+const (
+	duelCoin = "coin"
+	duelDice = "dice"
+)
+
+// Duel statuses
+const (
+	duelPending  = "pending"
+	duelDeclined = "declined"
+	duelExpired  = "expired"
+	duelResolved = "resolved"
+)
+
+var errDuelClosed = errors.New("duel already answered")
+
+func init() {
+	commands.RegisterCommand("duel", Duel)
+	commands.RegisterComponent("duel", DuelButton)
+}
+
+// duel is a duels row
+type duel struct {
+	ID           int64
+	GuildID      string
+	ChallengerID string
+	OpponentID   string
+	Amount       int64
+	Mode         string
+	ServerSeed   string
+	EscrowID     int64
+	Status       string
+	ExpiresAt    time.Time
+}
+
+// clientSeed ties the duel's rolls to both players
+func (d *duel) clientSeed() string {
+	return d.ChallengerID + ":" + d.OpponentID
+}
+
+// resolve decides the winner with the duel's fair RNG and describes the rolls
+func (d *duel) resolve() (string, string) {
+	rng := utils.NewFairRNG(d.ServerSeed, d.clientSeed(), d.ID)
+
+	if d.Mode == duelDice {
+		// Ties are rolled again
+		for {
+			challengerRoll, opponentRoll := rng.Intn(100)+1, rng.Intn(100)+1
+			if challengerRoll == opponentRoll {
+				continue
+			}
+			rolls := fmt.Sprintf("<@%s> rolled **%d**, <@%s> rolled **%d**.", d.ChallengerID, challengerRoll, d.OpponentID, opponentRoll)
+			if challengerRoll > opponentRoll {
+				return d.ChallengerID, rolls
+			}
+			return d.OpponentID, rolls
+		}
+	}
+
+	// The challenger is heads
+	if rng.Intn(2) == 0 {
+		return d.ChallengerID, "The coin landed on **heads**."
+	}
+	return d.OpponentID, "The coin landed on **tails**."
+}
+
+func (d *duel) footer() *discordgo.MessageEmbedFooter {
+	return &discordgo.MessageEmbedFooter{
+		Text: fmt.Sprintf("Duel #%d • Server seed hash %.16s… • Client seed %s • Nonce %d",
+			d.ID, utils.HashSeed(d.ServerSeed), d.clientSeed(), d.ID),
+	}
+}
+
+func lockDuel(tx *sql.Tx, duelID int64) (*duel, error) {
+	d := &duel{ID: duelID}
+	err := tx.QueryRow(`
+		SELECT guild_id, challenger_id, opponent_id, amount, mode, server_seed, challenger_escrow_id, status, expires_at
+		FROM duels
+		WHERE duel_id = $1
+		FOR UPDATE
+	`, duelID).Scan(&d.GuildID, &d.ChallengerID, &d.OpponentID, &d.Amount, &d.Mode, &d.ServerSeed, &d.EscrowID, &d.Status, &d.ExpiresAt)
+	return d, err
+}
+
+// closeDuel refunds the challenger's stake and marks the duel declined or expired
+func closeDuel(tx *sql.Tx, d *duel, status string) error {
+	// The escrow service may have refunded it already
+	if _, err := utils.RefundEscrow(tx, d.EscrowID); err != nil && !errors.Is(err, utils.ErrEscrowClosed) {
+		return err
+	}
+	_, err := tx.Exec(`
+		UPDATE duels SET status = $1, resolved_at = NOW() WHERE duel_id = $2
+	`, status, d.ID)
+	return err
+}
+
+// acceptDuel holds the opponent's stake, settles both escrows and pays the winner
+func acceptDuel(tx *sql.Tx, d *duel) (string, string, error) {
+	// Lock both wallets in a fixed order so two duels between the same pair can't deadlock
+	members := []string{d.ChallengerID, d.OpponentID}
+	sort.Strings(members)
+	for _, userID := range members {
+		if _, err := utils.LockBalance(tx, d.GuildID, userID); err != nil {
+			return "", "", err
+		}
+	}
+
+	opponentEscrow, err := utils.HoldEscrow(tx, d.GuildID, d.OpponentID, d.Amount, gameDuel, utils.ReasonDuelStake, duelTimeout)
+	if err != nil {
+		return "", "", err
+	}
+
+	var pot int64
+	for _, escrowID := range []int64{d.EscrowID, opponentEscrow} {
+		escrow, err := utils.ReleaseEscrow(tx, escrowID)
+		if err != nil {
+			return "", "", err
+		}
+		pot += escrow.Amount
+	}
+
+	winnerID, rolls := d.resolve()
+	_, err = utils.ApplyChange(tx, utils.BalanceChange{
+		GuildID: d.GuildID, UserID: winnerID, Amount: pot,
+		Reason: utils.ReasonDuelPayout, ActorID: d.OpponentID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE duels
+		SET status = $1, opponent_escrow_id = $2, winner_id = $3, resolved_at = NOW()
+		WHERE duel_id = $4
+	`, duelResolved, opponentEscrow, winnerID, d.ID)
+	return winnerID, rolls, err
+}
+
+// Duel challenges another member to a coin flip or dice roll for equal stakes
+func Duel(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	if len(args) < 3 {
+		s.ChannelMessageSend(m.ChannelID, "Usage: .duel <@user> <amount> [coin|dice]")
+		return
+	}
+
+	opponentID, err := utils.ExtractUserID(args[1])
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Invalid mention. Please use a proper mention (e.g., @username).")
+		return
+	}
+	if opponentID == m.Author.ID {
+		s.ChannelMessageSend(m.ChannelID, "You cannot duel yourself.")
+		return
+	}
+
+	opponent, err := s.User(opponentID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "User not found. Please check the mention.")
+		return
+	}
+	if opponent.Bot {
+		s.ChannelMessageSend(m.ChannelID, "You cannot duel a bot.")
+		return
+	}
+
+	mode := duelCoin
+	if len(args) > 3 {
+		mode = strings.ToLower(args[3])
+		if mode != duelCoin && mode != duelDice {
+			s.ChannelMessageSend(m.ChannelID, "Usage: .duel <@user> <amount> [coin|dice]")
+			return
+		}
+	}
+
+	amount, _, ok := parseBet(b, s, m, gameDuel, args[2])
+	if !ok {
+		return
+	}
+
+	if err := b.Economy.EnsureMember(m.GuildID, opponent); err != nil {
+		log.Printf("Error ensuring duel opponent: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	serverSeed, err := utils.NewServerSeed()
+	if err != nil {
+		log.Printf("Error creating duel seed: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	d := &duel{
+		GuildID: m.GuildID, ChallengerID: m.Author.ID, OpponentID: opponentID,
+		Amount: amount, Mode: mode, ServerSeed: serverSeed, Status: duelPending,
+		ExpiresAt: time.Now().Add(duelTimeout),
+	}
+	err = b.Economy.WithTx(func(tx *sql.Tx) error {
+		var err error
+		d.EscrowID, err = utils.HoldEscrow(tx, m.GuildID, m.Author.ID, amount, gameDuel, utils.ReasonDuelStake, duelTimeout)
+		if err != nil {
+			return err
+		}
+
+		return tx.QueryRow(`
+			INSERT INTO duels (guild_id, challenger_id, opponent_id, amount, mode, server_seed, challenger_escrow_id, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING duel_id
+		`, d.GuildID, d.ChallengerID, d.OpponentID, d.Amount, d.Mode, d.ServerSeed, d.EscrowID, d.ExpiresAt).Scan(&d.ID)
+	})
+	if err != nil {
+		betError(b, s, m, err)
+		return
+	}
+
+	currency := economy.GuildSettings(b, m.GuildID)
+	id := strconv.FormatInt(d.ID, 10)
+	_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("<@%s>, you have been challenged!", opponentID),
+		Embeds: []*discordgo.MessageEmbed{{
+			Title: "⚔️ Duel",
+			Description: fmt.Sprintf("<@%s> challenges <@%s> to a %s for %s each.\nThe winner takes %s. Expires <t:%d:R>.",
+				d.ChallengerID, d.OpponentID, duelModeName(mode), currency.Format(amount), currency.Format(amount*2), d.ExpiresAt.Unix()),
+			Color:  0xffa500,
+			Footer: d.footer(),
+		}},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{Label: "Accept", Style: discordgo.SuccessButton, CustomID: commands.CustomID("duel", "accept", id)},
+					discordgo.Button{Label: "Decline", Style: discordgo.DangerButton, CustomID: commands.CustomID("duel", "decline", id)},
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Error sending duel challenge: %v", err)
+	}
+}
+
+// DuelButton handles accept and decline. args are the action and the duel ID.
+// The opponent can accept or decline, the challenger can only withdraw.
+func DuelButton(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) < 2 {
+		return
+	}
+	action := args[0]
+	duelID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return
+	}
+
+	userID := utils.InteractionUserID(i)
+	var d *duel
+	var winnerID, rolls, status string
+	var notAllowed bool
+	err = b.Economy.WithTx(func(tx *sql.Tx) error {
+		var err error
+		d, err = lockDuel(tx, duelID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case userID != d.OpponentID && (userID != d.ChallengerID || action == "accept"):
+			notAllowed = true
+			return nil
+		case d.Status != duelPending:
+			return errDuelClosed
+		case time.Now().After(d.ExpiresAt):
+			status = duelExpired
+			return closeDuel(tx, d, duelExpired)
+		case action != "accept":
+			status = duelDeclined
+			return closeDuel(tx, d, duelDeclined)
+		}
+
+		status = duelResolved
+		winnerID, rolls, err = acceptDuel(tx, d)
+		return err
+	})
+
+	switch {
+	case notAllowed:
+		utils.RespondEphemeral(s, i, "This duel isn't yours to answer.")
+		return
+	case errors.Is(err, errDuelClosed):
+		utils.RespondEphemeral(s, i, "This duel has already been answered.")
+		return
+	case errors.Is(err, utils.ErrEscrowClosed):
+		// The escrow service refunded the challenger before the duel was marked expired
+		b.Economy.WithTx(func(tx *sql.Tx) error {
+			_, err := tx.Exec(`UPDATE duels SET status = $1, resolved_at = NOW() WHERE duel_id = $2 AND status = $3`,
+				duelExpired, duelID, duelPending)
+			return err
+		})
+		status = duelExpired
+	case errors.Is(err, utils.ErrInsufficientFunds):
+		utils.RespondEphemeral(s, i, fmt.Sprintf("You don't have enough %s to accept this duel.", economy.GuildSettings(b, i.GuildID).CurrencyName))
+		return
+	case err != nil:
+		log.Printf("Error answering duel %d: %v", duelID, err)
+		utils.RespondEphemeral(s, i, "An error occurred. Please try again.")
+		return
+	}
+
+	currency := economy.GuildSettings(b, i.GuildID)
+	embed := &discordgo.MessageEmbed{Title: "⚔️ Duel", Footer: d.footer()}
+	switch status {
+	case duelExpired:
+		embed.Description = fmt.Sprintf("This challenge expired. <@%s> was refunded %s.", d.ChallengerID, currency.Format(d.Amount))
+		embed.Color = 0x808080
+	case duelDeclined:
+		who := "declined"
+		if userID == d.ChallengerID {
+			who = "withdrawn"
+		}
+		embed.Description = fmt.Sprintf("The challenge was %s. <@%s> was refunded %s.", who, d.ChallengerID, currency.Format(d.Amount))
+		embed.Color = 0xff0000
+	default:
+		embed.Description = fmt.Sprintf("%s\n\n🏆 <@%s> wins %s!", rolls, winnerID, currency.Format(d.Amount*2))
+		embed.Color = 0x00ff00
+		// Reveal the seed so anyone can check the result
+		embed.Footer.Text += "\nServer seed " + d.ServerSeed
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+	if err != nil {
+		log.Printf("Error updating duel message: %v", err)
+	}
+}
+
+func duelModeName(mode string) string {
+	if mode == duelDice {
+		return "dice roll"
+	}
+	return "coin flip"
+}
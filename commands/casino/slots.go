@@ -0,0 +1,132 @@
+package casino
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/commands/economy"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+type slotSymbol struct {
+	Emoji  string
+	Weight int
+	Triple float64 // multiplier for three in a row
+}
+
+// Reel symbols, rarest last. Multipliers are scaled by slotsScale so the game
+// returns exactly (100 - house edge)% over time.
+var slotSymbols = []slotSymbol{
+	{"🍒", 30, 5},
+	{"🍋", 25, 8},
+	{"🔔", 20, 12},
+	{"⭐", 13, 25},
+	{"💎", 8, 60},
+	{"7️⃣", 4, 200},
+}
+
+// Two cherries on the first two reels pay this multiplier
+const slotsCherryPair = 1.5
+
+var slotsWeightTotal int
+var slotsScale float64
+
+func init() {
+	commands.RegisterCommand("slots", Slots)
+
+	for _, symbol := range slotSymbols {
+		slotsWeightTotal += symbol.Weight
+	}
+
+	// Expected return of the unscaled paytable
+	total := float64(slotsWeightTotal)
+	var rtp float64
+	for _, symbol := range slotSymbols {
+		p := float64(symbol.Weight) / total
+		rtp += p * p * p * symbol.Triple
+	}
+	cherry := float64(slotSymbols[0].Weight) / total
+	rtp += cherry * cherry * (1 - cherry) * slotsCherryPair
+	slotsScale = 1 / rtp
+}
+
+// spinReel picks a symbol by weight
+func spinReel(rng *utils.FairRNG) int {
+	n := rng.Intn(slotsWeightTotal)
+	for i, symbol := range slotSymbols {
+		if n < symbol.Weight {
+			return i
+		}
+		n -= symbol.Weight
+	}
+	return len(slotSymbols) - 1
+}
+
+// slotsMultiplier returns the unscaled multiplier for a spin
+func slotsMultiplier(reels [3]int) float64 {
+	if reels[0] == reels[1] && reels[1] == reels[2] {
+		return slotSymbols[reels[0]].Triple
+	}
+	if reels[0] == 0 && reels[1] == 0 {
+		return slotsCherryPair
+	}
+	return 0
+}
+
+// Slots spins three weighted reels
+func Slots(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	if len(args) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Usage: .slots <bet>")
+		return
+	}
+
+	bet, settings, ok := parseBet(b, s, m, gameSlots, args[1])
+	if !ok {
+		return
+	}
+
+	var reels [3]int
+	gameID, payout, round, newBalance, err := playInstant(b, m.GuildID, m.Author.ID, gameSlots, bet, func(rng *utils.FairRNG) int64 {
+		for i := range reels {
+			reels[i] = spinReel(rng)
+		}
+		multiplier := slotsMultiplier(reels)
+		if multiplier == 0 {
+			return 0
+		}
+		return applyEdge(float64(bet)*multiplier*slotsScale, settings.HouseEdge)
+	})
+	if err != nil {
+		betError(b, s, m, err)
+		return
+	}
+
+	currency := economy.GuildSettings(b, m.GuildID)
+	symbols := make([]string, len(reels))
+	for i, reel := range reels {
+		symbols[i] = slotSymbols[reel].Emoji
+	}
+
+	result := fmt.Sprintf("You lost %s.", currency.Format(bet))
+	color := 0xff0000
+	if payout > 0 {
+		result = fmt.Sprintf("You won %s! (x%.2f)", currency.Format(payout), math.Floor(float64(payout)/float64(bet)*100)/100)
+		color = 0x00ff00
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+		Title:       "🎰 Slots",
+		Description: fmt.Sprintf("**[ %s ]**\n\n%s\nBalance: %s", strings.Join(symbols, " | "), result, currency.Format(newBalance)),
+		Color:       color,
+		Footer:      fairFooter(gameID, round),
+	})
+}
@@ -2,11 +2,13 @@ package commands
 
 var CommandCategories = map[string][]string{
 	"General":      {"help", "commandlist", "usd", "btc", "remindme"},
-	"Economy":      {"balance", "work", "transfer", "flip", "setdailyrole", "removedailyrole", "listdailyroles"},
+	"Economy":      {"balance", "work", "jobs", "apply", "job", "transfer", "flip", "transactions", "leaderboard", "rank", "deposit", "withdraw", "shop", "buy", "inventory", "shopitem", "lottery", "roleincome", "season", "economy", "setdailyrole", "removedailyrole", "listdailyroles"},
+	"Casino":       {"blackjack", "slots", "dice", "duel", "casino"},
+	"Levels":       {"level", "voicetime", "rep"},
 	"EPL":          {"epltable", "nextmatch"},
 	"F1":           {"f1", "f1results", "f1standings", "f1wdc", "f1wcc", "qualiresults", "nextf1session", "f1sub"},
 	"Fpl":          {"fplstandings", "setfplleague"},
-	"Moderation":   {"kick", "mute", "unmute", "voicemute", "vunmute", "ban", "unban"},
-	"Admin":        {"setadmin", "add", "take", "disable", "enable"},
-	"Roles":        {"createrole", "setrole", "removerole", "inrole", "roleinfo"},
+	"Moderation":   {"kick", "mute", "unmute", "voicemute", "vunmute", "ban", "unban", "warn"},
+	"Admin":        {"setadmin", "add", "take", "setbalance", "grantrole", "disable", "enable", "setmodchannel"},
+	"Roles":        {"createrole", "setrole", "removerole", "inrole", "roleinfo", "myroles"},
 }
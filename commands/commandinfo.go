@@ -42,14 +42,14 @@ var CommandDetails = map[string]CommandInfo{
 	"balance": {
 		Name:        "balance",
 		Aliases:     []string{"bal"},
-		Description: "Shows your coin balance",
+		Description: "Shows your coin balance, bank balance and work streak",
 		Usage:       ".balance [user]",
 		Category:    "Economy",
 	},
 	"work": {
 		Name:        "work",
 		Aliases:     []string{},
-		Description: "Work to earn coins",
+		Description: "Work to earn coins. Working again soon after the cooldown builds a streak that raises the payout",
 		Usage:       ".work",
 		Category:    "Economy",
 	},
@@ -67,6 +67,174 @@ var CommandDetails = map[string]CommandInfo{
 		Usage:       ".flip <amount|all>",
 		Category:    "Economy",
 	},
+	"transactions": {
+		Name:        "transactions",
+		Aliases:     []string{"tx", "statement"},
+		Description: "Shows ledger entries with running balance. Admins can view other members and filter by reason (work, flip, transfer, admin_add, admin_take, ...) and date",
+		Usage:       ".transactions [user] [page] [reason:<reason>] [from:YYYY-MM-DD] [to:YYYY-MM-DD]",
+		Category:    "Economy",
+	},
+	"leaderboard": {
+		Name:        "leaderboard",
+		Aliases:     []string{"lb", "top"},
+		Description: "Shows the richest members in the server and your position",
+		Usage:       ".leaderboard [page]",
+		Category:    "Economy",
+	},
+	"rank": {
+		Name:        "rank",
+		Aliases:     []string{},
+		Description: "Shows a member's leaderboard position and the gap to the next rank",
+		Usage:       ".rank [user]",
+		Category:    "Economy",
+	},
+	"lottery": {
+		Name:        "lottery",
+		Aliases:     []string{"lotto"},
+		Description: "Shows the weekly lottery and sells tickets. Admins can enable it, set the schedule, ticket price and announcement channel",
+		Usage:       ".lottery [buy [amount]|enable|disable|schedule <day> <HH:MM>|price <amount>|channel <#channel>]",
+		Category:    "Economy",
+	},
+	"season": {
+		Name:        "season",
+		Aliases:     []string{},
+		Description: "Shows the current season and past winners. Admins can end the season, archiving the leaderboard, awarding a role to the top members and resetting or scaling balances",
+		Usage:       ".season [history [number]|end|top <n>|role <role|none>|mode reset|scale <percent>]",
+		Category:    "Economy",
+	},
+	"roleincome": {
+		Name:        "roleincome",
+		Aliases:     []string{},
+		Description: "Lists roles that earn passive income. Admins can make a role pay its members every hour or day",
+		Usage:       ".roleincome [set <role> <amount> <hourly|daily>|remove <role>]",
+		Category:    "Economy",
+	},
+	"jobs": {
+		Name:        "jobs",
+		Aliases:     []string{},
+		Description: "Lists the server's jobs with their pay, cooldown and requirements, and your job level",
+		Usage:       ".jobs",
+		Category:    "Economy",
+	},
+	"apply": {
+		Name:        "apply",
+		Aliases:     []string{},
+		Description: "Applies for a job if you meet its requirements. Changing jobs starts again from level 1",
+		Usage:       ".apply <job|none>",
+		Category:    "Economy",
+	},
+	"job": {
+		Name:        "job",
+		Aliases:     []string{},
+		Description: "Adds or removes a job (Admin only)",
+		Usage:       ".job add <min pay> <max pay> <name> [cooldown:<hours>] [balance:<n>] [role:<@role>] [shifts:<n>] [| description] | .job remove <job>",
+		Category:    "Economy",
+	},
+	"deposit": {
+		Name:        "deposit",
+		Aliases:     []string{"dep"},
+		Description: "Moves coins from your wallet into the bank, where they earn daily interest",
+		Usage:       ".deposit <amount|all>",
+		Category:    "Economy",
+	},
+	"withdraw": {
+		Name:        "withdraw",
+		Aliases:     []string{"with"},
+		Description: "Moves coins from the bank back into your wallet",
+		Usage:       ".withdraw <amount|all>",
+		Category:    "Economy",
+	},
+	"blackjack": {
+		Name:        "blackjack",
+		Aliases:     []string{"bj"},
+		Description: "Plays a hand of blackjack against the dealer. Blackjack pays 3:2 and the dealer stands on 17",
+		Usage:       ".blackjack <bet>",
+		Category:    "Casino",
+	},
+	"slots": {
+		Name:        "slots",
+		Aliases:     []string{},
+		Description: "Spins three reels. Three of a kind or two cherries pay out",
+		Usage:       ".slots <bet>",
+		Category:    "Casino",
+	},
+	"dice": {
+		Name:        "dice",
+		Aliases:     []string{},
+		Description: "Rolls 1-100 and pays by the odds of your target",
+		Usage:       ".dice <bet> <over|under> <target>",
+		Category:    "Casino",
+	},
+	"duel": {
+		Name:        "duel",
+		Aliases:     []string{},
+		Description: "Challenges another member to a coin flip or dice roll. Both stakes are held until the duel is settled, winner takes all",
+		Usage:       ".duel <@user> <amount> [coin|dice]",
+		Category:    "Casino",
+	},
+	"casino": {
+		Name:        "casino",
+		Aliases:     []string{},
+		Description: "Shows casino limits and your provably fair seeds, verifies past games. Admins can set limits, the house edge and disable games",
+		Usage:       ".casino [seed [client seed]|verify <game id>|enable|disable|minbet|maxbet|edge]",
+		Category:    "Casino",
+	},
+	"level": {
+		Name:        "level",
+		Aliases:     []string{"lvl", "xp"},
+		Description: "Shows a member's chat level and XP, or the XP leaderboard. Admins can set the XP rate, cooldown and curve, level-up announcements, excluded channels, role multipliers and level roles",
+		Usage:       ".level [@user|top [page]|config|enable|disable|rate <min> <max>|cooldown <seconds>|curve <base> <exponent>|announce <mode>|exclude|include <#channel>|multiplier <role> <x|none>|role <level> <role|none>|roles stack|replace]",
+		Category:    "Levels",
+	},
+	"voicetime": {
+		Name:        "voicetime",
+		Aliases:     []string{"vt"},
+		Description: "Shows a member's active time in voice, or the voice time leaderboard. Admins can pay XP and coins per active minute",
+		Usage:       ".voicetime [@user|top [page]|rewards <xp per minute> <coins per minute>]",
+		Category:    "Levels",
+	},
+	"rep": {
+		Name:        "rep",
+		Aliases:     []string{"reputation"},
+		Description: "Gives a member a reputation point once a day, never to yourself or to the same member twice in a row. Shows reputation, the latest reps and the leaderboard. Admins can award roles at rep milestones",
+		Usage:       ".rep [@user [reason]|show [@user]|top [page]|milestones|milestone <reps> <role|none>]",
+		Category:    "Levels",
+	},
+	"shop": {
+		Name:        "shop",
+		Aliases:     []string{},
+		Description: "Lists the items for sale in this server",
+		Usage:       ".shop",
+		Category:    "Economy",
+	},
+	"buy": {
+		Name:        "buy",
+		Aliases:     []string{},
+		Description: "Buys an item from the shop",
+		Usage:       ".buy <item name or #id>",
+		Category:    "Economy",
+	},
+	"inventory": {
+		Name:        "inventory",
+		Aliases:     []string{"inv"},
+		Description: "Shows the items you own",
+		Usage:       ".inventory [user]",
+		Category:    "Economy",
+	},
+	"shopitem": {
+		Name:        "shopitem",
+		Aliases:     []string{},
+		Description: "Adds, restocks or removes shop items. Items can grant a role, optionally for a limited time (Admin only)",
+		Usage:       ".shopitem add <price> <name> [stock:<n>] [role:<@role>] [duration:<7d>] [| description] | .shopitem stock <item> <n|unlimited> | .shopitem remove <item>",
+		Category:    "Economy",
+	},
+	"economy": {
+		Name:        "economy",
+		Aliases:     []string{"eco"},
+		Description: "Shows or changes the server's economy settings, shows the treasury, exports or imports balances as CSV or JSON, or resets every balance after confirmation (Admin only)",
+		Usage:       ".economy config [currency|emoji|starting|workmin|workmax|cooldown|maxbet|interest|interestcap|streakbonus|streakmax|streakgrace|tax|dailycap|memberdays|accountdays|confirmabove] [value] | .economy treasury | .economy export [csv|json] | .economy import (with a file attached) | .economy reset",
+		Category:    "Economy",
+	},
 	"remindme": {
 		Name:        "remindme",
 		Aliases:     []string{},
@@ -228,6 +396,13 @@ var CommandDetails = map[string]CommandInfo{
 		Usage:       ".unban <user>",
 		Category:    "Moderation",
 	},
+	"warn": {
+		Name:        "warn",
+		Aliases:     []string{"w"},
+		Description: "Warns a user and records the warning",
+		Usage:       ".warn <user> [reason]",
+		Category:    "Moderation",
+	},
 	"setadmin": {
 		Name:        "setadmin",
 		Aliases:     []string{},
@@ -246,7 +421,21 @@ var CommandDetails = map[string]CommandInfo{
 		Name:        "take",
 		Aliases:     []string{},
 		Description: "Takes coins from a user's balance",
-		Usage:       ".take <user> <amount>",
+		Usage:       ".take <user> <amount|all>",
+		Category:    "Admin",
+	},
+	"setbalance": {
+		Name:        "setbalance",
+		Aliases:     []string{"setbal"},
+		Description: "Sets a user's balance to an exact amount",
+		Usage:       ".setbalance <user> <amount>",
+		Category:    "Admin",
+	},
+	"grantrole": {
+		Name:        "grantrole",
+		Aliases:     []string{},
+		Description: "Adds coins to every member of a role",
+		Usage:       ".grantrole <amount> <role>",
 		Category:    "Admin",
 	},
 	"disable": {
@@ -263,6 +452,13 @@ var CommandDetails = map[string]CommandInfo{
 		Usage:       ".enable <command|category> <name>",
 		Category:    "Admin",
 	},
+	"setmodchannel": {
+		Name:        "setmodchannel",
+		Aliases:     []string{},
+		Description: "Sets the channel that message reports are sent to",
+		Usage:       ".setmodchannel <#channel>",
+		Category:    "Admin",
+	},
 	"createrole": {
 		Name:        "createrole",
 		Aliases:     []string{},
@@ -273,8 +469,15 @@ var CommandDetails = map[string]CommandInfo{
 	"setrole": {
 		Name:        "setrole",
 		Aliases:     []string{},
-		Description: "Assigns a role to a user",
-		Usage:       ".setrole <user> <role>",
+		Description: "Assigns a role to a user, optionally for a limited time",
+		Usage:       ".setrole <user> <role> [duration:<7d>]",
+		Category:    "Roles",
+	},
+	"myroles": {
+		Name:        "myroles",
+		Aliases:     []string{},
+		Description: "Shows your timed roles and the time left on each",
+		Usage:       ".myroles",
 		Category:    "Roles",
 	},
 	"inrole": {
@@ -76,38 +76,7 @@ func CommandList(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, a
 		return
 	}
 	
-	// Send the first page
-	msg, err := s.ChannelMessageSendEmbed(m.ChannelID, pages[0])
-	if err != nil {
+	if err := SendPaginated(s, m.ChannelID, m.GuildID, m.Author.ID, pages, 0); err != nil {
 		log.Printf("Error sending command list: %v", err)
-		return
-	}
-	
-	// If there's only one page, no need for pagination
-	if len(pages) <= 1 {
-		return
-	}
-	
-	// Add reactions for pagination
-	err = s.MessageReactionAdd(msg.ChannelID, msg.ID, "⬅️")
-	if err != nil {
-		log.Printf("Error adding left reaction: %v", err)
 	}
-	
-	err = s.MessageReactionAdd(msg.ChannelID, msg.ID, "➡️")
-	if err != nil {
-		log.Printf("Error adding right reaction: %v", err)
-	}
-	
-	// Store pagination state
-	state := &PaginationState{
-		UserID:      m.Author.ID,
-		MessageID:   msg.ID,
-		CurrentPage: 0,
-		TotalPages:  len(pages),
-		Pages:       pages,
-		GuildID:     m.GuildID,
-	}
-	
-	paginationManager.AddState(state)
-}
\ No newline at end of file
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"log"
+	"strings"
+
+	"DiscordBot/bot"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+// ComponentFunc handles a button, select menu or modal submit interaction.
+// args holds the state segments that were encoded into the custom ID.
+type ComponentFunc func(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, args []string)
+
+// ComponentMap maps a custom ID prefix to its handler
+var ComponentMap = make(map[string]ComponentFunc)
+
+const (
+	customIDSeparator = ":"
+	// Discord rejects custom IDs longer than this
+	maxCustomIDLength = 100
+)
+
+// customIDEscaper keeps state segments from being split on the separator
+var customIDEscaper = strings.NewReplacer("%", "%25", customIDSeparator, "%3A")
+var customIDUnescaper = strings.NewReplacer("%3A", customIDSeparator, "%25", "%")
+
+// RegisterComponent registers a handler for every custom ID starting with prefix.
+// Handlers are registered from init functions, so they are available again as
+// soon as the bot restarts and buttons on old messages keep working.
+func RegisterComponent(prefix string, handler ComponentFunc) {
+	if strings.Contains(prefix, customIDSeparator) {
+		log.Fatalf("component prefix %q must not contain %q", prefix, customIDSeparator)
+	}
+	ComponentMap[prefix] = handler
+}
+
+// CustomID builds a namespaced custom ID of the form "prefix:arg1:arg2".
+// All state a handler needs must be encoded here, nothing is kept in memory.
+func CustomID(prefix string, args ...string) string {
+	parts := make([]string, 0, len(args)+1)
+	parts = append(parts, prefix)
+	for _, arg := range args {
+		parts = append(parts, customIDEscaper.Replace(arg))
+	}
+
+	id := strings.Join(parts, customIDSeparator)
+	if len(id) > maxCustomIDLength {
+		log.Printf("Custom ID %q exceeds %d characters and will be rejected by Discord", id, maxCustomIDLength)
+	}
+	return id
+}
+
+// ParseCustomID splits a custom ID built by CustomID into its prefix and state
+func ParseCustomID(id string) (string, []string) {
+	parts := strings.Split(id, customIDSeparator)
+	args := make([]string, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		args = append(args, customIDUnescaper.Replace(part))
+	}
+	return parts[0], args
+}
+
+// HandleComponent routes message component and modal submit interactions to
+// the handler registered for their custom ID prefix
+func HandleComponent(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var customID string
+	switch i.Type {
+	case discordgo.InteractionMessageComponent:
+		customID = i.MessageComponentData().CustomID
+	case discordgo.InteractionModalSubmit:
+		customID = i.ModalSubmitData().CustomID
+	default:
+		return
+	}
+
+	prefix, args := ParseCustomID(customID)
+	handler, ok := ComponentMap[prefix]
+	if !ok {
+		log.Printf("No component handler registered for custom ID %q", customID)
+		utils.RespondEphemeral(s, i, "This interaction is no longer available.")
+		return
+	}
+
+	handler(b, s, i, args)
+}
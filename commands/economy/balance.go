@@ -20,13 +20,12 @@ func Balance(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args
 		return // Don't respond to DMs
 	}
 
-	var targetUserID string
+	target := m.Author
 	// Check if a mention is provided & validate
 	if len(args) >= 2 {
 		// Extract and validate the target user mention
 		recipientMention := args[1]
-		var err error
-		targetUserID, err = utils.ExtractUserID(recipientMention)
+		targetUserID, err := utils.ExtractUserID(recipientMention)
 		if err != nil {
 			s.ChannelMessageSend(m.ChannelID, "Invalid mention / use. Please use a proper mention (e.g., @username).")
 			return
@@ -38,46 +37,38 @@ func Balance(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args
 			s.ChannelMessageSend(m.ChannelID, "mentioned user is not in this server.")
 			return
 		}
-	} else {
-		// If no mention is provided, use the author's ID
-		targetUserID = m.Author.ID
+		target = member.User
 	}
 
-	// Ensure user exists in global users table
-	_, err := b.Db.Exec(`
-		INSERT INTO users (user_id, username, avatar, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		ON CONFLICT (user_id) DO UPDATE SET
-			username = EXCLUDED.username,
-			avatar = EXCLUDED.avatar,
-			updated_at = NOW()
-	`, targetUserID, m.Author.Username, m.Author.AvatarURL(""))
+	// Ensure user exists in the users and guild_members tables
+	if err := b.Economy.EnsureMember(m.GuildID, target); err != nil {
+		log.Printf("Error ensuring member: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	balance, err := b.Economy.Balance(m.GuildID, target.ID)
 	if err != nil {
-		log.Printf("Error upserting user: %v", err)
+		log.Printf("Error querying balance: %v", err)
 		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
 		return
 	}
 
-	// Ensure user exists in guild_members table
-	_, err = b.Db.Exec(`
-		INSERT INTO guild_members (guild_id, user_id, joined_at)
-		VALUES ($1, $2, NOW())
-		ON CONFLICT (guild_id, user_id) DO NOTHING
-	`, m.GuildID, targetUserID)
+	bank, err := b.Economy.BankBalance(m.GuildID, target.ID)
 	if err != nil {
-		log.Printf("Error ensuring guild member: %v", err)
+		log.Printf("Error querying bank balance: %v", err)
 		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
 		return
 	}
 
-	// Now query their balance (will exist due to prior insert)
-	var balance int
-	err = b.Db.QueryRow("SELECT balance FROM guild_members WHERE guild_id = $1 AND user_id = $2", m.GuildID, targetUserID).Scan(&balance)
+	settings := GuildSettings(b, m.GuildID)
+	streak, err := memberStreak(b, s, m.GuildID, target.ID, settings)
 	if err != nil {
-		log.Printf("Error querying balance: %v", err)
+		log.Printf("Error querying work streak: %v", err)
 		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
 		return
 	}
 
-	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("<@%s>'s balance: %d coins", targetUserID, balance))
-}
\ No newline at end of file
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("<@%s>'s balance: %s | Bank: %s | 🔥 Streak: %d",
+		target.ID, settings.Format(balance), settings.Format(bank), streak))
+}
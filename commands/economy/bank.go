@@ -0,0 +1,92 @@
+package economy
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	commands.RegisterCommand("deposit", Deposit, "dep")
+	commands.RegisterCommand("withdraw", Withdraw, "with")
+}
+
+// Deposit moves coins from the wallet into the bank, out of reach of .flip
+func Deposit(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	moveCoins(b, s, m, args, utils.AccountWallet, utils.AccountBank)
+}
+
+// Withdraw moves coins from the bank back into the wallet
+func Withdraw(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	moveCoins(b, s, m, args, utils.AccountBank, utils.AccountWallet)
+}
+
+// moveCoins handles "<amount|all>" for both directions
+func moveCoins(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string, from, to string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	usage := fmt.Sprintf("Usage: .%s <amount|all>", args[0])
+	if len(args) < 2 {
+		s.ChannelMessageSend(m.ChannelID, usage)
+		return
+	}
+
+	// Parse the amount up front; "all" is resolved against the locked balance
+	amount := int64(0)
+	if args[1] != "all" {
+		var err error
+		amount, err = strconv.ParseInt(args[1], 10, 64)
+		if err != nil || amount <= 0 {
+			s.ChannelMessageSend(m.ChannelID, "Invalid amount. "+usage)
+			return
+		}
+	}
+
+	if err := b.Economy.EnsureMember(m.GuildID, m.Author); err != nil {
+		log.Printf("Error ensuring member: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+	fromAfter, toAfter, err := b.Economy.MoveBetweenAccounts(m.GuildID, m.Author.ID, from, to, func(available int64) (int64, error) {
+		if args[1] == "all" {
+			amount = available
+		}
+		if amount <= 0 {
+			return 0, errInvalidAmount
+		}
+		if available < amount {
+			return 0, utils.ErrInsufficientFunds
+		}
+		return amount, nil
+	})
+
+	wallet, bank := fromAfter, toAfter
+	if from == utils.AccountBank {
+		wallet, bank = toAfter, fromAfter
+	}
+
+	switch {
+	case errors.Is(err, errInvalidAmount):
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You have nothing in your %s.", from))
+	case errors.Is(err, utils.ErrInsufficientFunds):
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Not enough %s in your %s.", settings.CurrencyName, from))
+	case err != nil:
+		log.Printf("Error moving coins from %s to %s: %v", from, to, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+	case from == utils.AccountWallet:
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Deposited %s. Wallet: %s | Bank: %s", settings.Format(amount), settings.Format(wallet), settings.Format(bank)))
+	default:
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Withdrew %s. Wallet: %s | Bank: %s", settings.Format(amount), settings.Format(wallet), settings.Format(bank)))
+	}
+}
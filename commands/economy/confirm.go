@@ -0,0 +1,97 @@
+package economy
+
+import (
+	"log"
+	"time"
+
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+// confirmation is an admin prompt with confirm and cancel buttons, as sent by
+// .economy reset, .economy import and .season end
+type confirmation struct {
+	adminID   string // the only member who may press the buttons
+	issuedAt  time.Time
+	timeout   time.Duration
+	expired   string // shown when a button is pressed after the timeout
+	cancelled string // shown when the cancel button is pressed
+	onCancel  func() // optional, runs before the prompt is closed
+}
+
+// accept answers a button press on the prompt. It returns true only for a
+// confirmation by the admin who asked, in time and still an admin, after
+// deferring the response; the caller then finishes with editConfirmMessage.
+func (c confirmation) accept(s *discordgo.Session, i *discordgo.InteractionCreate, action string) bool {
+	userID := utils.InteractionUserID(i)
+	if userID != c.adminID {
+		utils.RespondEphemeral(s, i, "Only the admin who started this can use these buttons.")
+		return false
+	}
+
+	if time.Since(c.issuedAt) > c.timeout {
+		updateConfirmMessage(s, i, c.expired, nil)
+		return false
+	}
+
+	if action != "confirm" {
+		if c.onCancel != nil {
+			c.onCancel()
+		}
+		updateConfirmMessage(s, i, c.cancelled, nil)
+		return false
+	}
+
+	// Permissions may have changed since the buttons were sent
+	hasAdmin, err := utils.CheckAdminPermission(s, i.GuildID, userID)
+	if err != nil || !hasAdmin {
+		utils.RespondEphemeral(s, i, "You are not authorized to use this command.")
+		return false
+	}
+
+	// The confirmed action rewrites balances and can outlast the interaction deadline
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err != nil {
+		log.Printf("Error deferring confirmation: %v", err)
+		return false
+	}
+	return true
+}
+
+// updateConfirmMessage replaces the prompt and removes its buttons. Without an
+// embed, the prompt's embed is kept for reference.
+func updateConfirmMessage(s *discordgo.Session, i *discordgo.InteractionCreate, content string, embed *discordgo.MessageEmbed) {
+	data := &discordgo.InteractionResponseData{
+		Content:    content,
+		Components: []discordgo.MessageComponent{},
+	}
+	if embed != nil {
+		data.Embeds = []*discordgo.MessageEmbed{embed}
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: data,
+	})
+	if err != nil {
+		log.Printf("Error updating confirmation message: %v", err)
+	}
+}
+
+// editConfirmMessage is updateConfirmMessage once accept has deferred the
+// response. The buttons are only removed when clearButtons is set, so a failed
+// action can be confirmed again.
+func editConfirmMessage(s *discordgo.Session, i *discordgo.InteractionCreate, content string, embed *discordgo.MessageEmbed, clearButtons bool) {
+	edit := &discordgo.WebhookEdit{Content: &content}
+	if embed != nil {
+		edit.Embeds = &[]*discordgo.MessageEmbed{embed}
+	}
+	if clearButtons {
+		edit.Components = &[]discordgo.MessageComponent{}
+	}
+	if _, err := s.InteractionResponseEdit(i.Interaction, edit); err != nil {
+		log.Printf("Error editing confirmation message: %v", err)
+	}
+}
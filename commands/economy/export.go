@@ -0,0 +1,458 @@
+package economy
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// The import buttons stop working after this long
+	importConfirmTimeout = 5 * time.Minute
+
+	maxImportSize    = 2 << 20 // bytes
+	maxImportMembers = 10000
+	importDiffLines  = 10 // largest changes listed in the dry run
+)
+
+var errImportClosed = errors.New("import already applied or cancelled")
+
+func init() {
+	economySubcommands["export"] = economyExport
+	economySubcommands["import"] = economyImport
+	commands.RegisterComponent("ecoimport", EconomyImportButton)
+}
+
+// economyExport sends every member's wallet and bank balance as a CSV or JSON file
+func economyExport(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	format := "csv"
+	if len(args) > 1 {
+		format = strings.ToLower(args[1])
+	}
+	if format != "csv" && format != "json" {
+		s.ChannelMessageSend(m.ChannelID, "Usage: .economy export [csv|json]")
+		return
+	}
+
+	members, err := b.Economy.ExportBalances(m.GuildID)
+	if err != nil {
+		log.Printf("Error exporting balances for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while exporting balances.")
+		return
+	}
+
+	var data bytes.Buffer
+	contentType := "text/csv"
+	if format == "json" {
+		contentType = "application/json"
+		encoder := json.NewEncoder(&data)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(members)
+	} else {
+		err = writeBalancesCSV(&data, members)
+	}
+	if err != nil {
+		log.Printf("Error encoding balances for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while exporting balances.")
+		return
+	}
+
+	_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("Exported the balances of %d members. Import them with `.economy import` and this file attached.", len(members)),
+		Files: []*discordgo.File{{
+			Name:        fmt.Sprintf("economy-%s-%s.%s", m.GuildID, time.Now().UTC().Format("2006-01-02"), format),
+			ContentType: contentType,
+			Reader:      &data,
+		}},
+	})
+	if err != nil {
+		log.Printf("Error sending balance export: %v", err)
+	}
+}
+
+func writeBalancesCSV(w io.Writer, members []utils.MemberBalances) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"user_id", "username", "balance", "bank_balance"})
+	for _, member := range members {
+		writer.Write([]string{
+			member.UserID, member.Username,
+			strconv.FormatInt(member.Balance, 10), strconv.FormatInt(member.Bank, 10),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// economyImport validates an attached export, shows what would change and asks the
+// admin to confirm before applying it
+func economyImport(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	if len(m.Attachments) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "Usage: .economy import with a CSV (`user_id,balance,bank_balance`) or JSON file attached, as produced by `.economy export`.")
+		return
+	}
+	attachment := m.Attachments[0]
+	if attachment.Size > maxImportSize {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("The file is too large. Imports can be at most %d KB.", maxImportSize>>10))
+		return
+	}
+
+	resp, err := http.Get(attachment.URL)
+	if err != nil {
+		log.Printf("Error downloading import file: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "Failed to download the attached file.")
+		return
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImportSize+1))
+	if err != nil || resp.StatusCode != http.StatusOK || len(data) > maxImportSize {
+		log.Printf("Error reading import file (status %d): %v", resp.StatusCode, err)
+		s.ChannelMessageSend(m.ChannelID, "Failed to download the attached file.")
+		return
+	}
+
+	members, err := parseBalances(attachment.Filename, data)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("The file is invalid, nothing was imported: %v", err))
+		return
+	}
+
+	current, err := b.Economy.ExportBalances(m.GuildID)
+	if err != nil {
+		log.Printf("Error querying balances for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	stored, err := json.Marshal(members)
+	if err != nil {
+		log.Printf("Error encoding import: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	// The parsed file is kept until the admin confirms, so the buttons don't depend on the attachment
+	var importID int64
+	err = b.Db.QueryRow(`
+		INSERT INTO economy_imports (guild_id, admin_id, file_name, balances)
+		VALUES ($1, $2, $3, $4)
+		RETURNING import_id
+	`, m.GuildID, m.Author.ID, attachment.Filename, stored).Scan(&importID)
+	if err != nil {
+		log.Printf("Error saving import: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+	id := strconv.FormatInt(importID, 10)
+	_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content: "⚠️ Dry run: nothing has changed yet. Members not in the file keep their balances. Apply this import?",
+		Embed:   importDiffEmbed(settings, attachment.Filename, current, members),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Apply import",
+						Style:    discordgo.DangerButton,
+						CustomID: commands.CustomID("ecoimport", "confirm", id),
+					},
+					discordgo.Button{
+						Label:    "Cancel",
+						Style:    discordgo.SecondaryButton,
+						CustomID: commands.CustomID("ecoimport", "cancel", id),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Error sending import dry run: %v", err)
+	}
+}
+
+// parseBalances reads a CSV or JSON export, detected from the file name or contents
+func parseBalances(filename string, data []byte) ([]utils.MemberBalances, error) {
+	var members []utils.MemberBalances
+	var err error
+	switch ext := strings.ToLower(path.Ext(filename)); {
+	case ext == ".json", ext != ".csv" && bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")):
+		members, err = parseBalancesJSON(data)
+	default:
+		members, err = parseBalancesCSV(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(members) == 0 {
+		return nil, errors.New("the file has no members")
+	}
+	if len(members) > maxImportMembers {
+		return nil, fmt.Errorf("the file has %d members, at most %d can be imported at once", len(members), maxImportMembers)
+	}
+	return members, nil
+}
+
+func parseBalancesCSV(data []byte) ([]utils.MemberBalances, error) {
+	// Spreadsheet programs often save CSV files with a byte order mark
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the CSV header: %v", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"user_id", "balance", "bank_balance"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("the CSV header is missing the `%s` column", name)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var members []utils.MemberBalances
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// csv.ParseError already names the line
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		member, err := validateImportRow(seen, record[columns["user_id"]], record[columns["balance"]], record[columns["bank_balance"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func parseBalancesJSON(data []byte) ([]utils.MemberBalances, error) {
+	// Numbers are kept as written so a missing or fractional balance is reported, not zeroed
+	var rows []struct {
+		UserID  string      `json:"user_id"`
+		Balance json.Number `json:"balance"`
+		Bank    json.Number `json:"bank_balance"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&rows); err != nil {
+		return nil, fmt.Errorf("the JSON must be a list of {\"user_id\", \"balance\", \"bank_balance\"} objects: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	members := make([]utils.MemberBalances, 0, len(rows))
+	for i, row := range rows {
+		member, err := validateImportRow(seen, row.UserID, row.Balance.String(), row.Bank.String())
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %v", i+1, err)
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// validateImportRow checks one member of an import, rejecting members listed twice
+func validateImportRow(seen map[string]bool, userID, balance, bank string) (utils.MemberBalances, error) {
+	member := utils.MemberBalances{UserID: strings.TrimSpace(userID)}
+	if _, err := strconv.ParseUint(member.UserID, 10, 64); err != nil || len(member.UserID) < 15 {
+		return member, fmt.Errorf("`%s` is not a Discord user ID", member.UserID)
+	}
+	if seen[member.UserID] {
+		return member, fmt.Errorf("user %s is listed more than once", member.UserID)
+	}
+	seen[member.UserID] = true
+
+	var err error
+	member.Balance, err = strconv.ParseInt(strings.TrimSpace(balance), 10, 64)
+	if err != nil || member.Balance < 0 {
+		return member, fmt.Errorf("balance `%s` must be a whole number of 0 or more", balance)
+	}
+	member.Bank, err = strconv.ParseInt(strings.TrimSpace(bank), 10, 64)
+	if err != nil || member.Bank < 0 {
+		return member, fmt.Errorf("bank balance `%s` must be a whole number of 0 or more", bank)
+	}
+	return member, nil
+}
+
+// importDiffEmbed summarises what an import would change, listing the largest changes
+func importDiffEmbed(settings *utils.EconomySettings, filename string, current, members []utils.MemberBalances) *discordgo.MessageEmbed {
+	before := make(map[string]utils.MemberBalances, len(current))
+	for _, member := range current {
+		before[member.UserID] = member
+	}
+
+	type change struct {
+		line string
+		size int64
+	}
+	var changes []change
+	var added, unchanged int
+	var walletDelta, bankDelta int64
+	for _, member := range members {
+		old, exists := before[member.UserID]
+		if !exists {
+			added++
+		} else if old.Balance == member.Balance && old.Bank == member.Bank {
+			unchanged++
+			continue
+		}
+
+		walletDelta += member.Balance - old.Balance
+		bankDelta += member.Bank - old.Bank
+		line := fmt.Sprintf("<@%s>: %s → %s", member.UserID, settings.Format(old.Balance), settings.Format(member.Balance))
+		if old.Bank != member.Bank {
+			line += fmt.Sprintf(", bank %s → %s", settings.Format(old.Bank), settings.Format(member.Bank))
+		}
+		if !exists {
+			line += " (new)"
+		}
+		changes = append(changes, change{line, absDelta(member.Balance-old.Balance) + absDelta(member.Bank-old.Bank)})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].size > changes[j].size })
+	var lines []string
+	for i, c := range changes {
+		if i == importDiffLines {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(changes)-importDiffLines))
+			break
+		}
+		lines = append(lines, c.line)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "Every balance in the file already matches.")
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       "Economy Import: " + filename,
+		Description: strings.Join(lines, "\n"),
+		Color:       0xffa500,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Members in File", Value: strconv.Itoa(len(members)), Inline: true},
+			{Name: "Changed", Value: fmt.Sprintf("%d (%d new)", len(changes), added), Inline: true},
+			{Name: "Unchanged", Value: strconv.Itoa(unchanged), Inline: true},
+			{Name: "Wallet Change", Value: formatDelta(settings, walletDelta), Inline: true},
+			{Name: "Bank Change", Value: formatDelta(settings, bankDelta), Inline: true},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: "New members are counted from 0 and receive the starting balance before the import"},
+	}
+}
+
+func absDelta(delta int64) int64 {
+	if delta < 0 {
+		return -delta
+	}
+	return delta
+}
+
+func formatDelta(settings *utils.EconomySettings, delta int64) string {
+	if delta < 0 {
+		return "-" + settings.Format(-delta)
+	}
+	return "+" + settings.Format(delta)
+}
+
+// EconomyImportButton handles the apply and cancel buttons of .economy import.
+// args are the action and the import ID.
+func EconomyImportButton(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) < 2 {
+		return
+	}
+	action := args[0]
+	importID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return
+	}
+
+	var adminID string
+	var createdAt time.Time
+	err = b.Db.QueryRow(`
+		SELECT admin_id, created_at FROM economy_imports WHERE import_id = $1 AND guild_id = $2
+	`, importID, i.GuildID).Scan(&adminID, &createdAt)
+	if err != nil {
+		log.Printf("Error querying import %d: %v", importID, err)
+		utils.RespondEphemeral(s, i, "This import could not be found.")
+		return
+	}
+
+	prompt := confirmation{
+		adminID:   adminID,
+		issuedAt:  createdAt,
+		timeout:   importConfirmTimeout,
+		expired:   "This import has expired. Run `.economy import` again.",
+		cancelled: "Import cancelled. Nothing was changed.",
+		onCancel: func() {
+			_, err := b.Db.Exec(`UPDATE economy_imports SET status = 'cancelled' WHERE import_id = $1 AND status = 'pending'`, importID)
+			if err != nil {
+				log.Printf("Error cancelling import %d: %v", importID, err)
+			}
+		},
+	}
+	if !prompt.accept(s, i, action) {
+		return
+	}
+	userID := utils.InteractionUserID(i)
+
+	changed := 0
+	err = b.Economy.WithTx(func(tx *sql.Tx) error {
+		var status string
+		var stored []byte
+		err := tx.QueryRow(`
+			SELECT status, balances FROM economy_imports WHERE import_id = $1 FOR UPDATE
+		`, importID).Scan(&status, &stored)
+		if err != nil {
+			return err
+		}
+		if status != "pending" {
+			return errImportClosed
+		}
+
+		var members []utils.MemberBalances
+		if err := json.Unmarshal(stored, &members); err != nil {
+			return err
+		}
+		changed, err = utils.SetBalances(tx, i.GuildID, userID, utils.ReasonAdminImport, members)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE economy_imports SET status = 'applied', applied_at = NOW() WHERE import_id = $1`, importID)
+		return err
+	})
+	if errors.Is(err, errImportClosed) {
+		editConfirmMessage(s, i, "This import was already applied or cancelled.", nil, true)
+		return
+	} else if err != nil {
+		log.Printf("Error applying import %d for guild %s: %v", importID, i.GuildID, err)
+		// Keep the buttons so the admin can try again
+		editConfirmMessage(s, i, "An error occurred while applying the import. Nothing was changed.", nil, false)
+		return
+	}
+	topBalances.invalidate(i.GuildID)
+
+	editConfirmMessage(s, i, fmt.Sprintf("Import applied by <@%s>. %d balances were changed.", userID, changed), nil, true)
+}
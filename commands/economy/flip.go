@@ -1,7 +1,7 @@
 package economy
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
@@ -9,12 +9,15 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"DiscordBot/bot"
 	"DiscordBot/commands"
+	"DiscordBot/utils"
 )
 
 func init() {
 	commands.RegisterCommand("flip", Flip)
 }
 
+var errInvalidAmount = errors.New("invalid amount")
+
 func Flip(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
 	// Ensure command is used in a guild
 	if m.GuildID == "" {
@@ -26,102 +29,72 @@ func Flip(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []s
 		return
 	}
 
-	// Ensure user exists in global users table
-	_, err := b.Db.Exec(`
-		INSERT INTO users (user_id, username, avatar, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		ON CONFLICT (user_id) DO UPDATE SET
-			username = EXCLUDED.username,
-			avatar = EXCLUDED.avatar,
-			updated_at = NOW()
-	`, m.Author.ID, m.Author.Username, m.Author.AvatarURL(""))
-	if err != nil {
-		log.Printf("Error upserting user: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
-		return
-	}
-
-	// Ensure user exists in guild_members table
-	_, err = b.Db.Exec(`
-		INSERT INTO guild_members (guild_id, user_id, joined_at)
-		VALUES ($1, $2, NOW())
-		ON CONFLICT (guild_id, user_id) DO NOTHING
-	`, m.GuildID, m.Author.ID)
-	if err != nil {
-		log.Printf("Error ensuring guild member: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
-		return
-	}
-
-	// Get the user's balance
-	var balance int
-	err = b.Db.QueryRow("SELECT balance FROM guild_members WHERE guild_id = $1 AND user_id = $2", m.GuildID, m.Author.ID).Scan(&balance)
-	if err == sql.ErrNoRows {
-		s.ChannelMessageSend(m.ChannelID, "You have 0 coins.")
-		return
-	} else if err != nil {
-		log.Printf("Error querying balance: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
-		return
-	}
-
-	// Handle "all" case by setting amount to the user's total balance
-	amount := 0
-	if args[1] == "all" {
-		amount = balance
-	} else {
-		// Parse the amount
-		_, err = fmt.Sscanf(args[1], "%d", &amount)
+	// Parse the amount up front; "all" is resolved against the locked balance
+	amount := int64(0)
+	if args[1] != "all" {
+		_, err := fmt.Sscanf(args[1], "%d", &amount)
 		if err != nil || amount <= 0 {
 			s.ChannelMessageSend(m.ChannelID, "Invalid amount. Usage: .flip <amount|all>")
 			return
 		}
 	}
 
-	// Check if the user has enough coins
-	if balance < amount {
-		s.ChannelMessageSend(m.ChannelID, "Not enough coins.")
+	settings := GuildSettings(b, m.GuildID)
+	if settings.FlipMaxBet > 0 && amount > settings.FlipMaxBet {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("The maximum bet is %s.", settings.Format(settings.FlipMaxBet)))
 		return
 	}
 
-	// Flip the coins
-	if rand.Intn(2) == 0 {
-		// User wins
-		_, err := b.Db.Exec("UPDATE guild_members SET balance = balance + $1 WHERE guild_id = $2 AND user_id = $3", amount, m.GuildID, m.Author.ID)
-		if err != nil {
-			log.Printf("Error updating balance: %v", err)
-			s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
-			return
-		}
+	if err := b.Economy.EnsureMember(m.GuildID, m.Author); err != nil {
+		log.Printf("Error ensuring member: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
 
-		// Log transaction
-		_, err = b.Db.Exec(`
-			INSERT INTO transactions (guild_id, user_id, amount, balance_before, balance_after, reason)
-			VALUES ($1, $2, $3, $4, $5, 'flip_win')
-		`, m.GuildID, m.Author.ID, amount, balance, balance+amount)
-		if err != nil {
-			log.Printf("Error logging transaction: %v", err)
+	// The balance row stays locked between the check and the update, so two
+	// quick flips can't both spend the same coins
+	won := false
+	_, newBalance, err := b.Economy.Mutate(utils.BalanceChange{
+		GuildID: m.GuildID,
+		UserID:  m.Author.ID,
+		Reason:  utils.ReasonFlip,
+		ActorID: m.Author.ID,
+	}, func(balance int64) (int64, error) {
+		// Handle "all" case by setting amount to the user's total balance
+		if args[1] == "all" {
+			amount = balance
+			if settings.FlipMaxBet > 0 && amount > settings.FlipMaxBet {
+				amount = settings.FlipMaxBet
+			}
+		}
+		if amount <= 0 {
+			return 0, errInvalidAmount
 		}
 
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You won %d coins! Your new balance is %d.", amount, balance+amount))
-	} else {
-		// User loses
-		_, err := b.Db.Exec("UPDATE guild_members SET balance = balance - $1 WHERE guild_id = $2 AND user_id = $3", amount, m.GuildID, m.Author.ID)
-		if err != nil {
-			log.Printf("Error updating balance: %v", err)
-			s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
-			return
+		// Check if the user has enough coins
+		if balance < amount {
+			return 0, utils.ErrInsufficientFunds
 		}
 
-		// Log transaction
-		_, err = b.Db.Exec(`
-			INSERT INTO transactions (guild_id, user_id, amount, balance_before, balance_after, reason)
-			VALUES ($1, $2, $3, $4, $5, 'flip_loss')
-		`, m.GuildID, m.Author.ID, -amount, balance, balance-amount)
-		if err != nil {
-			log.Printf("Error logging transaction: %v", err)
+		// Flip the coins
+		won = rand.Intn(2) == 0
+		if won {
+			return amount, nil
 		}
+		return -amount, nil
+	})
 
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You lost %d coins! Your new balance is %d.", amount, balance-amount))
+	switch {
+	case errors.Is(err, errInvalidAmount):
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You have %s.", settings.Format(0)))
+	case errors.Is(err, utils.ErrInsufficientFunds):
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Not enough %s.", settings.CurrencyName))
+	case err != nil:
+		log.Printf("Error flipping coins: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+	case won:
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You won %s! Your new balance is %s.", settings.Format(amount), settings.Format(newBalance)))
+	default:
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You lost %s! Your new balance is %s.", settings.Format(amount), settings.Format(newBalance)))
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,221 @@
+package economy
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const jobUsage = "Usage:\n" +
+	"`.job add <min pay> <max pay> <name> [cooldown:<hours>] [balance:<n>] [role:<@role>] [shifts:<n>] [| description]`\n" +
+	"`.job remove <job>`"
+
+func init() {
+	commands.RegisterCommand("job", JobAdmin)
+}
+
+// JobAdmin lets admins manage the guild's jobs
+func JobAdmin(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	// Check if the user has administrator permissions
+	hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking admin status: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if !hasAdmin {
+		s.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command.")
+		return
+	}
+
+	if len(args) < 3 {
+		s.ChannelMessageSend(m.ChannelID, jobUsage)
+		return
+	}
+
+	switch strings.ToLower(args[1]) {
+	case "add":
+		addJob(b, s, m, args[2:])
+	case "remove", "delete":
+		removeJob(b, s, m, strings.Join(args[2:], " "))
+	default:
+		s.ChannelMessageSend(m.ChannelID, jobUsage)
+	}
+}
+
+// addJob parses "<min> <max> <name> [options] [| description]" and creates the job
+func addJob(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	if len(args) < 3 {
+		s.ChannelMessageSend(m.ChannelID, jobUsage)
+		return
+	}
+
+	minPayout, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || minPayout < 0 {
+		s.ChannelMessageSend(m.ChannelID, "Minimum pay must be 0 or more.\n"+jobUsage)
+		return
+	}
+	maxPayout, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || maxPayout < minPayout {
+		s.ChannelMessageSend(m.ChannelID, "Maximum pay must be at least the minimum pay.\n"+jobUsage)
+		return
+	}
+
+	// Everything after "|" is the description
+	rest := strings.Join(args[2:], " ")
+	description := ""
+	if idx := strings.Index(rest, "|"); idx >= 0 {
+		description = strings.TrimSpace(rest[idx+1:])
+		rest = rest[:idx]
+	}
+
+	j := &job{MinPayout: minPayout, MaxPayout: maxPayout, Description: description}
+	var nameParts []string
+	for _, word := range strings.Fields(rest) {
+		lower := strings.ToLower(word)
+		switch {
+		case strings.HasPrefix(lower, "cooldown:"):
+			hours, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(lower, "cooldown:"), "h"), 10, 64)
+			if err != nil || hours < 0 || hours > 168 {
+				s.ChannelMessageSend(m.ChannelID, "Cooldown must be between 0 and 168 hours.")
+				return
+			}
+			j.CooldownHours.Int64, j.CooldownHours.Valid = hours, true
+		case strings.HasPrefix(lower, "balance:"):
+			balance, err := strconv.ParseInt(strings.TrimPrefix(lower, "balance:"), 10, 64)
+			if err != nil || balance < 0 {
+				s.ChannelMessageSend(m.ChannelID, "Required balance must be 0 or more.")
+				return
+			}
+			j.RequiredBalance = balance
+		case strings.HasPrefix(lower, "role:"):
+			role, err := utils.FindRole(s, m.GuildID, word[len("role:"):])
+			if err != nil || role == nil {
+				s.ChannelMessageSend(m.ChannelID, "Role not found. Use a role mention, e.g. `role:@Verified`.")
+				return
+			}
+			j.RequiredRoleID = role.ID
+		case strings.HasPrefix(lower, "shifts:"):
+			shifts, err := strconv.Atoi(strings.TrimPrefix(lower, "shifts:"))
+			if err != nil || shifts < 0 {
+				s.ChannelMessageSend(m.ChannelID, "Required shifts must be 0 or more.")
+				return
+			}
+			j.RequiredShifts = shifts
+		default:
+			nameParts = append(nameParts, word)
+		}
+	}
+
+	j.Name = strings.Join(nameParts, " ")
+	if j.Name == "" {
+		s.ChannelMessageSend(m.ChannelID, "Please give the job a name.\n"+jobUsage)
+		return
+	}
+	if len(j.Name) > 100 {
+		s.ChannelMessageSend(m.ChannelID, "Job names must be 100 characters or fewer.")
+		return
+	}
+	if _, err := strconv.ParseInt(strings.TrimPrefix(j.Name, "#"), 10, 64); err == nil {
+		s.ChannelMessageSend(m.ChannelID, "Job names can't be plain numbers, they are used for job IDs.")
+		return
+	}
+	if strings.EqualFold(j.Name, "none") {
+		s.ChannelMessageSend(m.ChannelID, "A job can't be called 'none', it's used to quit a job.")
+		return
+	}
+
+	var count int
+	if err := b.Db.QueryRow(`SELECT COUNT(*) FROM jobs WHERE guild_id = $1 AND active`, m.GuildID).Scan(&count); err != nil {
+		log.Printf("Error counting jobs: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+	if count >= maxJobs {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("This server already has %d jobs. Remove one first.", maxJobs))
+		return
+	}
+
+	if _, err := findJob(b.Db, m.GuildID, j.Name); err == nil {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("A job named '%s' already exists.", j.Name))
+		return
+	} else if !errors.Is(err, errJobNotFound) {
+		log.Printf("Error querying job: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	var cooldown, roleID interface{}
+	if j.CooldownHours.Valid {
+		cooldown = j.CooldownHours.Int64
+	}
+	if j.RequiredRoleID != "" {
+		roleID = j.RequiredRoleID
+	}
+
+	err = b.Db.QueryRow(`
+		INSERT INTO jobs (guild_id, name, description, min_payout, max_payout, cooldown_hours,
+			required_balance, required_role_id, required_shifts, created_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10)
+		RETURNING job_id
+	`, m.GuildID, j.Name, j.Description, j.MinPayout, j.MaxPayout, cooldown,
+		j.RequiredBalance, roleID, j.RequiredShifts, m.Author.ID).Scan(&j.ID)
+	if err != nil {
+		log.Printf("Error creating job: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while creating the job.")
+		return
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Added job #%d %s", j.ID, j.Name),
+		Description: j.describe(GuildSettings(b, m.GuildID)),
+		Color:       0x00ff00,
+	})
+}
+
+// removeJob retires a job. Members who had it go back to the standard .work pay.
+func removeJob(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, query string) {
+	j, err := findJob(b.Db, m.GuildID, query)
+	if errors.Is(err, errJobNotFound) {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Job '%s' not found.", query))
+		return
+	} else if err != nil {
+		log.Printf("Error querying job: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	var employees int64
+	err = b.Economy.WithTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`UPDATE jobs SET active = FALSE WHERE job_id = $1`, j.ID); err != nil {
+			return err
+		}
+		res, err := tx.Exec(`UPDATE guild_members SET job_id = NULL, job_shifts = 0 WHERE guild_id = $1 AND job_id = $2`, m.GuildID, j.ID)
+		if err != nil {
+			return err
+		}
+		employees, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		log.Printf("Error removing job: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Removed the job '%s'. %d members lost it.", j.Name, employees))
+}
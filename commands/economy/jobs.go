@@ -0,0 +1,324 @@
+package economy
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	maxJobs = 25 // one embed field per job in .jobs
+
+	// Every jobShiftsPerLevel shifts in the same job raise its pay by jobLevelBonus percent
+	jobShiftsPerLevel = 10
+	jobLevelBonus     = 10
+	jobMaxLevel       = 10
+)
+
+var errJobNotFound = errors.New("job not found")
+
+func init() {
+	commands.RegisterCommand("jobs", Jobs)
+	commands.RegisterCommand("apply", Apply)
+}
+
+// job is a row of jobs
+type job struct {
+	ID              int64
+	Name            string
+	Description     string
+	MinPayout       int64
+	MaxPayout       int64
+	CooldownHours   sql.NullInt64 // invalid means the guild's work cooldown
+	RequiredBalance int64
+	RequiredRoleID  string // empty when no role is required
+	RequiredShifts  int
+}
+
+const jobColumns = `job_id, name, COALESCE(description, ''), min_payout, max_payout, cooldown_hours, required_balance, COALESCE(required_role_id::text, ''), required_shifts`
+
+func scanJob(row rowScanner) (*job, error) {
+	j := &job{}
+	err := row.Scan(&j.ID, &j.Name, &j.Description, &j.MinPayout, &j.MaxPayout, &j.CooldownHours,
+		&j.RequiredBalance, &j.RequiredRoleID, &j.RequiredShifts)
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// findJob looks an active job up by "#id", id or name
+func findJob(q utils.Querier, guildID, query string) (*job, error) {
+	sqlQuery := `SELECT ` + jobColumns + ` FROM jobs WHERE guild_id = $1 AND active AND `
+	var arg interface{}
+	if id, err := strconv.ParseInt(strings.TrimPrefix(query, "#"), 10, 64); err == nil {
+		sqlQuery += `job_id = $2`
+		arg = id
+	} else {
+		sqlQuery += `lower(name) = lower($2)`
+		arg = query
+	}
+
+	j, err := scanJob(q.QueryRow(sqlQuery, guildID, arg))
+	if err == sql.ErrNoRows {
+		return nil, errJobNotFound
+	}
+	return j, err
+}
+
+// getMemberJob returns the member's job, or nil if they don't have one, along
+// with their shifts in that job and their shifts overall
+func getMemberJob(q utils.Querier, guildID, userID string) (*job, int, int, error) {
+	var jobID sql.NullInt64
+	var jobShifts, totalShifts int
+	err := q.QueryRow(`
+		SELECT job_id, COALESCE(job_shifts, 0), COALESCE(total_shifts, 0)
+		FROM guild_members
+		WHERE guild_id = $1 AND user_id = $2
+	`, guildID, userID).Scan(&jobID, &jobShifts, &totalShifts)
+	if err != nil || !jobID.Valid {
+		return nil, jobShifts, totalShifts, err
+	}
+
+	j, err := findJob(q, guildID, strconv.FormatInt(jobID.Int64, 10))
+	if errors.Is(err, errJobNotFound) {
+		// The job was removed after the member got it, e.g. while they were applying.
+		// Clear it so shifts stop counting towards a job that no longer exists.
+		_, err = q.Exec(`
+			UPDATE guild_members SET job_id = NULL, job_shifts = 0
+			WHERE guild_id = $1 AND user_id = $2 AND job_id = $3
+		`, guildID, userID, jobID.Int64)
+		return nil, 0, totalShifts, err
+	}
+	return j, jobShifts, totalShifts, err
+}
+
+// cooldownHours returns the job's cooldown, falling back to the guild's work cooldown
+func (j *job) cooldownHours(settings *utils.EconomySettings) int {
+	if j == nil || !j.CooldownHours.Valid {
+		return settings.WorkCooldownHours
+	}
+	return int(j.CooldownHours.Int64)
+}
+
+// describe renders the job's pay and requirements for .jobs
+func (j *job) describe(settings *utils.EconomySettings) string {
+	text := fmt.Sprintf("Pays %s - %s every %d hours", settings.Format(j.MinPayout), settings.Format(j.MaxPayout), j.cooldownHours(settings))
+
+	var requirements []string
+	if j.RequiredBalance > 0 {
+		requirements = append(requirements, settings.Format(j.RequiredBalance))
+	}
+	if j.RequiredRoleID != "" {
+		requirements = append(requirements, fmt.Sprintf("<@&%s>", j.RequiredRoleID))
+	}
+	if j.RequiredShifts > 0 {
+		requirements = append(requirements, fmt.Sprintf("%d shifts worked", j.RequiredShifts))
+	}
+	if len(requirements) > 0 {
+		text += "\nRequires: " + strings.Join(requirements, " • ")
+	}
+
+	if j.Description != "" {
+		text = j.Description + "\n" + text
+	}
+	return text
+}
+
+// jobLevel returns the level reached after a number of shifts in the same job
+func jobLevel(shifts int) int {
+	level := 1 + shifts/jobShiftsPerLevel
+	if level > jobMaxLevel {
+		level = jobMaxLevel
+	}
+	return level
+}
+
+// jobLevelMultiplier is the pay multiplier of a job level
+func jobLevelMultiplier(level int) float64 {
+	return 1 + float64((level-1)*jobLevelBonus)/100
+}
+
+// Jobs lists the guild's jobs along with the member's current job and level
+func Jobs(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	rows, err := b.Db.Query(`SELECT `+jobColumns+` FROM jobs WHERE guild_id = $1 AND active ORDER BY min_payout, job_id`, m.GuildID)
+	if err != nil {
+		log.Printf("Error querying jobs: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving jobs.")
+		return
+	}
+	defer rows.Close()
+
+	var jobs []*job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			log.Printf("Error scanning job: %v", err)
+			continue
+		}
+		jobs = append(jobs, j)
+	}
+
+	if len(jobs) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "This server has no jobs yet. `.work` pays the standard rate.")
+		return
+	}
+
+	current, jobShifts, totalShifts, err := getMemberJob(b.Db, m.GuildID, m.Author.ID)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error querying member job: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving jobs.")
+		return
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+	embed := &discordgo.MessageEmbed{
+		Title:       "Jobs",
+		Description: "Apply for a job with `.apply <job name or #id>`",
+		Color:       0x00ff00,
+	}
+	for _, j := range jobs {
+		name := fmt.Sprintf("#%d %s", j.ID, j.Name)
+		if current != nil && current.ID == j.ID {
+			name += " (your job)"
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: name, Value: j.describe(settings)})
+	}
+
+	footer := fmt.Sprintf("You have no job • %d shifts worked", totalShifts)
+	if current != nil {
+		level := jobLevel(jobShifts)
+		footer = fmt.Sprintf("You work as %s, level %d (+%d%% pay) • %d shifts worked",
+			current.Name, level, (level-1)*jobLevelBonus, totalShifts)
+		if level < jobMaxLevel {
+			footer += fmt.Sprintf(" • Next level in %d shifts", jobShiftsPerLevel-jobShifts%jobShiftsPerLevel)
+		}
+	}
+	embed.Footer = &discordgo.MessageEmbedFooter{Text: footer}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, embed)
+}
+
+// Apply switches the member to a job if they meet its requirements. Changing jobs
+// starts again from level 1.
+func Apply(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	if len(args) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Usage: .apply <job name or #id|none>")
+		return
+	}
+	query := strings.Join(args[1:], " ")
+
+	if err := b.Economy.EnsureMember(m.GuildID, m.Author); err != nil {
+		log.Printf("Error ensuring member: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	current, _, totalShifts, err := getMemberJob(b.Db, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error querying member job: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if strings.EqualFold(query, "none") {
+		if current == nil {
+			s.ChannelMessageSend(m.ChannelID, "You don't have a job.")
+			return
+		}
+		_, err = b.Db.Exec(`
+			UPDATE guild_members SET job_id = NULL, job_shifts = 0 WHERE guild_id = $1 AND user_id = $2
+		`, m.GuildID, m.Author.ID)
+		if err != nil {
+			log.Printf("Error leaving job: %v", err)
+			s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+			return
+		}
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You quit your job as %s.", current.Name))
+		return
+	}
+
+	j, err := findJob(b.Db, m.GuildID, query)
+	if errors.Is(err, errJobNotFound) {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Job '%s' not found. Use `.jobs` to see what's available.", query))
+		return
+	} else if err != nil {
+		log.Printf("Error querying job: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if current != nil && current.ID == j.ID {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You already work as %s.", j.Name))
+		return
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+	if j.RequiredShifts > totalShifts {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You need %d shifts worked to apply for %s. You have %d.", j.RequiredShifts, j.Name, totalShifts))
+		return
+	}
+
+	if j.RequiredBalance > 0 {
+		balance, err := b.Economy.Balance(m.GuildID, m.Author.ID)
+		if err != nil {
+			log.Printf("Error querying balance: %v", err)
+			s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+			return
+		}
+		if balance < j.RequiredBalance {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You need a balance of %s to apply for %s.", settings.Format(j.RequiredBalance), j.Name))
+			return
+		}
+	}
+
+	if j.RequiredRoleID != "" {
+		member, err := s.GuildMember(m.GuildID, m.Author.ID)
+		if err != nil {
+			log.Printf("Error fetching member: %v", err)
+			s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+			return
+		}
+
+		hasRole := false
+		for _, roleID := range member.Roles {
+			if roleID == j.RequiredRoleID {
+				hasRole = true
+				break
+			}
+		}
+		if !hasRole {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You need the <@&%s> role to apply for %s.", j.RequiredRoleID, j.Name))
+			return
+		}
+	}
+
+	_, err = b.Db.Exec(`
+		UPDATE guild_members SET job_id = $3, job_shifts = 0 WHERE guild_id = $1 AND user_id = $2
+	`, m.GuildID, m.Author.ID, j.ID)
+	if err != nil {
+		log.Printf("Error applying for job: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You're hired! You now work as %s. %s", j.Name, j.describe(settings)))
+}
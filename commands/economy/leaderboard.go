@@ -0,0 +1,228 @@
+package economy
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	leaderboardSize    = 100
+	leaderboardPerPage = 10
+	// Keeps repeated .leaderboard calls from hitting the database every time
+	leaderboardCacheTTL = 30 * time.Second
+)
+
+func init() {
+	commands.RegisterCommand("leaderboard", Leaderboard, "lb", "top")
+	commands.RegisterCommand("rank", Rank)
+}
+
+type leaderboardEntry struct {
+	UserID  string
+	Balance int64
+}
+
+type cachedLeaderboard struct {
+	entries   []leaderboardEntry
+	fetchedAt time.Time
+}
+
+// leaderboardCache holds the top balances per guild for a short time
+type leaderboardCache struct {
+	mu     sync.Mutex
+	guilds map[string]cachedLeaderboard
+}
+
+var topBalances = &leaderboardCache{guilds: make(map[string]cachedLeaderboard)}
+
+// get returns the guild's top balances, reading idx_gm_guild_balance_desc when the cache is stale
+func (lc *leaderboardCache) get(db *sql.DB, guildID string) ([]leaderboardEntry, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if cached, ok := lc.guilds[guildID]; ok && time.Since(cached.fetchedAt) < leaderboardCacheTTL {
+		return cached.entries, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT user_id, balance
+		FROM guild_members
+		WHERE guild_id = $1
+		ORDER BY balance DESC
+		LIMIT $2
+	`, guildID, leaderboardSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []leaderboardEntry
+	for rows.Next() {
+		var entry leaderboardEntry
+		if err := rows.Scan(&entry.UserID, &entry.Balance); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lc.guilds[guildID] = cachedLeaderboard{entries: entries, fetchedAt: time.Now()}
+	return entries, nil
+}
+
+// invalidate drops the guild's cached balances, e.g. after a reset
+func (lc *leaderboardCache) invalidate(guildID string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	delete(lc.guilds, guildID)
+}
+
+// memberRank returns a member's balance, position and the balance of the member
+// directly above them (0 when they are first)
+func memberRank(db *sql.DB, guildID, userID string) (balance int64, position int, nextBalance int64, err error) {
+	err = db.QueryRow(`
+		SELECT COALESCE((SELECT balance FROM guild_members WHERE guild_id = $1 AND user_id = $2), 0)
+	`, guildID, userID).Scan(&balance)
+	if err != nil {
+		return
+	}
+
+	err = db.QueryRow(`
+		SELECT COUNT(*) + 1, COALESCE(MIN(balance), 0)
+		FROM guild_members
+		WHERE guild_id = $1 AND balance > $2
+	`, guildID, balance).Scan(&position, &nextBalance)
+	return
+}
+
+// Leaderboard shows the guild's top balances and the caller's position
+func Leaderboard(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	page := 1
+	if len(args) >= 2 {
+		var err error
+		page, err = strconv.Atoi(args[1])
+		if err != nil || page < 1 {
+			s.ChannelMessageSend(m.ChannelID, "Usage: .leaderboard [page]")
+			return
+		}
+	}
+
+	entries, err := topBalances.get(b.Db, m.GuildID)
+	if err != nil {
+		log.Printf("Error querying leaderboard: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving the leaderboard.")
+		return
+	}
+
+	if len(entries) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "Nobody has any coins yet.")
+		return
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+
+	_, position, _, err := memberRank(b.Db, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error querying rank: %v", err)
+	}
+
+	var pages []*discordgo.MessageEmbed
+	for start := 0; start < len(entries); start += leaderboardPerPage {
+		end := start + leaderboardPerPage
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		lines := make([]string, 0, end-start)
+		for i, entry := range entries[start:end] {
+			lines = append(lines, fmt.Sprintf("**#%d** <@%s> - %s", start+i+1, entry.UserID, settings.Format(entry.Balance)))
+		}
+
+		pages = append(pages, &discordgo.MessageEmbed{
+			Title:       "Leaderboard",
+			Description: strings.Join(lines, "\n"),
+			Color:       0xFFD700, // Gold
+		})
+	}
+
+	// Add footer to each page with page number and the caller's position
+	for i, p := range pages {
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(pages))
+		if position > 0 {
+			footer += fmt.Sprintf(" • Your rank: #%d", position)
+		}
+		p.Footer = &discordgo.MessageEmbedFooter{Text: footer}
+	}
+
+	if page > len(pages) {
+		page = len(pages)
+	}
+
+	if err := commands.SendPaginated(s, m.ChannelID, m.GuildID, m.Author.ID, pages, page-1); err != nil {
+		log.Printf("Error sending leaderboard: %v", err)
+	}
+}
+
+// Rank shows a member's position and how far they are behind the next rank
+func Rank(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	targetUserID := m.Author.ID
+	if len(args) >= 2 {
+		var err error
+		targetUserID, err = utils.ExtractUserID(args[1])
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Invalid mention / use. Please use a proper mention (e.g., @username).")
+			return
+		}
+	}
+
+	balance, position, nextBalance, err := memberRank(b.Db, m.GuildID, targetUserID)
+	if err != nil {
+		log.Printf("Error querying rank: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "Rank",
+		Description: fmt.Sprintf("<@%s>", targetUserID),
+		Color:       0xFFD700, // Gold
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Position", Value: fmt.Sprintf("#%d", position), Inline: true},
+			{Name: "Balance", Value: settings.Format(balance), Inline: true},
+		},
+	}
+
+	if position > 1 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Next Rank",
+			Value:  fmt.Sprintf("%s behind", settings.Format(nextBalance-balance)),
+			Inline: true,
+		})
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, embed)
+}
@@ -0,0 +1,304 @@
+package economy
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Most tickets a member can buy at once
+const maxTicketsPerPurchase = 100
+
+const lotteryUsage = "Usage:\n" +
+	"`.lottery` - show the current round\n" +
+	"`.lottery buy [amount]` - buy tickets\n" +
+	"Admins: `.lottery enable|disable`, `.lottery schedule <day> <HH:MM>` (UTC), `.lottery price <amount>`, `.lottery channel <#channel>`"
+
+var errLotteryClosed = errors.New("no open lottery")
+
+func init() {
+	commands.RegisterCommand("lottery", Lottery, "lotto")
+}
+
+// Lottery shows the guild's lottery, sells tickets and lets admins configure it
+func Lottery(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	if len(args) < 2 {
+		showLottery(b, s, m)
+		return
+	}
+
+	switch strings.ToLower(args[1]) {
+	case "buy":
+		buyTickets(b, s, m, args[2:])
+	case "enable", "disable", "schedule", "price", "channel":
+		configureLottery(b, s, m, args[1:])
+	default:
+		s.ChannelMessageSend(m.ChannelID, lotteryUsage)
+	}
+}
+
+func showLottery(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate) {
+	config, err := utils.LoadLotteryConfig(b.Db, m.GuildID)
+	if err != nil {
+		log.Printf("Error loading lottery config for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	lottery, err := utils.CurrentLottery(b.Db, m.GuildID, false)
+	if err == sql.ErrNoRows {
+		s.ChannelMessageSend(m.ChannelID, "There is no lottery running in this server.\n"+lotteryUsage)
+		return
+	} else if err != nil {
+		log.Printf("Error querying lottery: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	var owned int64
+	err = b.Db.QueryRow(`
+		SELECT COALESCE(SUM(quantity), 0) FROM lottery_tickets WHERE lottery_id = $1 AND user_id = $2
+	`, lottery.ID, m.Author.ID).Scan(&owned)
+	if err != nil {
+		log.Printf("Error querying lottery tickets: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+	chance := "0%"
+	if lottery.Tickets > 0 {
+		chance = fmt.Sprintf("%.2f%%", float64(owned)/float64(lottery.Tickets)*100)
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+		Title:       "🎟️ Lottery",
+		Description: "Buy tickets with `.lottery buy [amount]`. One ticket wins the whole pot.",
+		Color:       0xffd700,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Pot", Value: settings.Format(lottery.Pot), Inline: true},
+			{Name: "Ticket Price", Value: settings.Format(config.TicketPrice), Inline: true},
+			{Name: "Tickets Sold", Value: strconv.FormatInt(lottery.Tickets, 10), Inline: true},
+			{Name: "Your Tickets", Value: fmt.Sprintf("%d (%s chance)", owned, chance), Inline: true},
+			{Name: "Draw", Value: fmt.Sprintf("<t:%d:F> (<t:%d:R>)", lottery.DrawAt.Unix(), lottery.DrawAt.Unix()), Inline: true},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Round #%d • Server seed hash %.16s… • Revealed after the draw", lottery.ID, utils.HashSeed(lottery.ServerSeed)),
+		},
+	})
+}
+
+func buyTickets(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	quantity := int64(1)
+	if len(args) > 0 {
+		n, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || n <= 0 || n > maxTicketsPerPurchase {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You can buy between 1 and %d tickets at a time.", maxTicketsPerPurchase))
+			return
+		}
+		quantity = n
+	}
+
+	if err := b.Economy.EnsureMember(m.GuildID, m.Author); err != nil {
+		log.Printf("Error ensuring member: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+	var lottery *utils.Lottery
+	var cost int64
+	err := b.Economy.WithTx(func(tx *sql.Tx) error {
+		config, err := utils.LoadLotteryConfig(tx, m.GuildID)
+		if err != nil {
+			return err
+		}
+		if !config.Enabled {
+			return errLotteryClosed
+		}
+
+		// The round stays locked so ticket numbers are handed out in order
+		lottery, err = utils.CurrentLottery(tx, m.GuildID, true)
+		if err == sql.ErrNoRows || (err == nil && !lottery.DrawAt.After(time.Now())) {
+			return errLotteryClosed
+		} else if err != nil {
+			return err
+		}
+
+		cost = config.TicketPrice * quantity
+		if _, err := utils.LockBalance(tx, m.GuildID, m.Author.ID); err != nil {
+			return err
+		}
+		_, err = utils.ApplyChange(tx, utils.BalanceChange{
+			GuildID: m.GuildID, UserID: m.Author.ID, Amount: -cost,
+			Reason: utils.ReasonLotteryTicket, ActorID: m.Author.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO lottery_tickets (lottery_id, guild_id, user_id, first_ticket, quantity)
+			VALUES ($1, $2, $3, $4, $5)
+		`, lottery.ID, m.GuildID, m.Author.ID, lottery.Tickets, quantity)
+		if err != nil {
+			return err
+		}
+
+		lottery.Tickets += quantity
+		lottery.Pot += cost
+		_, err = tx.Exec(`
+			UPDATE lotteries SET tickets = $1, pot = $2 WHERE lottery_id = $3
+		`, lottery.Tickets, lottery.Pot, lottery.ID)
+		return err
+	})
+	if errors.Is(err, errLotteryClosed) {
+		s.ChannelMessageSend(m.ChannelID, "There is no lottery open for tickets right now.")
+		return
+	} else if errors.Is(err, utils.ErrInsufficientFunds) {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Not enough %s to buy %d tickets.", settings.CurrencyName, quantity))
+		return
+	} else if err != nil {
+		log.Printf("Error buying lottery tickets: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You bought %d tickets for %s. The pot is now %s, drawn <t:%d:R>.",
+		quantity, settings.Format(cost), settings.Format(lottery.Pot), lottery.DrawAt.Unix()))
+}
+
+// configureLottery handles the admin subcommands
+func configureLottery(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Check if the user has administrator permissions
+	hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking admin status: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if !hasAdmin {
+		s.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command.")
+		return
+	}
+
+	var reply string
+	err = b.Economy.WithTx(func(tx *sql.Tx) error {
+		switch strings.ToLower(args[0]) {
+		case "enable", "disable":
+			enabled := strings.ToLower(args[0]) == "enable"
+			if _, err := tx.Exec(`UPDATE guilds SET lottery_enabled = $1 WHERE guild_id = $2`, enabled, m.GuildID); err != nil {
+				return err
+			}
+			if !enabled {
+				reply = "Lottery disabled. The current round, if any, is still drawn but no new round will start."
+				return nil
+			}
+
+			config, err := utils.LoadLotteryConfig(tx, m.GuildID)
+			if err != nil {
+				return err
+			}
+			if err := utils.OpenLottery(tx, m.GuildID, config.NextDraw(time.Now())); err != nil {
+				return err
+			}
+			reply = "Lottery enabled. Members can buy tickets with `.lottery buy [amount]`."
+
+		case "schedule":
+			if len(args) < 3 {
+				reply = "Usage: .lottery schedule <day> <HH:MM> (UTC), e.g. `.lottery schedule sunday 20:00`"
+				return nil
+			}
+			weekday, ok := parseWeekday(args[1])
+			clock, err := time.Parse("15:04", args[2])
+			if !ok || err != nil {
+				reply = "Invalid schedule. Use a weekday and a 24-hour UTC time, e.g. `.lottery schedule sunday 20:00`."
+				return nil
+			}
+			minute := clock.Hour()*60 + clock.Minute()
+
+			_, err = tx.Exec(`UPDATE guilds SET lottery_weekday = $1, lottery_minute = $2 WHERE guild_id = $3`, int(weekday), minute, m.GuildID)
+			if err != nil {
+				return err
+			}
+
+			// Move the open round to the new schedule
+			config, err := utils.LoadLotteryConfig(tx, m.GuildID)
+			if err != nil {
+				return err
+			}
+			next := config.NextDraw(time.Now())
+			_, err = tx.Exec(`UPDATE lotteries SET draw_at = $1 WHERE guild_id = $2 AND status = 'open'`, next, m.GuildID)
+			if err != nil {
+				return err
+			}
+			reply = fmt.Sprintf("The lottery is now drawn every %s at %s UTC. Next draw: <t:%d:F>.", weekday, args[2], next.Unix())
+
+		case "price":
+			if len(args) < 2 {
+				reply = "Usage: .lottery price <amount>"
+				return nil
+			}
+			price, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil || price <= 0 {
+				reply = "Ticket price must be a number greater than 0."
+				return nil
+			}
+			if _, err := tx.Exec(`UPDATE guilds SET lottery_ticket_price = $1 WHERE guild_id = $2`, price, m.GuildID); err != nil {
+				return err
+			}
+			reply = fmt.Sprintf("Lottery tickets now cost %s.", GuildSettings(b, m.GuildID).Format(price))
+
+		case "channel":
+			if len(args) < 2 {
+				reply = "Usage: .lottery channel <#channel>"
+				return nil
+			}
+			channelID := strings.TrimSuffix(strings.TrimPrefix(args[1], "<#"), ">")
+			channel, err := s.Channel(channelID)
+			if err != nil || channel.GuildID != m.GuildID {
+				reply = "Invalid channel. Please mention a channel in this server."
+				return nil
+			}
+			if _, err := tx.Exec(`UPDATE guilds SET lottery_channel_id = $1 WHERE guild_id = $2`, channelID, m.GuildID); err != nil {
+				return err
+			}
+			reply = fmt.Sprintf("Lottery draws will be announced in <#%s>.", channelID)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error configuring lottery for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "Error updating the lottery. Please try again later.")
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, reply)
+}
+
+// parseWeekday accepts full or three-letter English day names
+func parseWeekday(input string) (time.Weekday, bool) {
+	input = strings.ToLower(input)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if input == name || input == name[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
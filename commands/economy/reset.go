@@ -0,0 +1,84 @@
+package economy
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+// The reset buttons stop working after this long
+const resetConfirmTimeout = time.Minute
+
+func init() {
+	economySubcommands["reset"] = economyReset
+	commands.RegisterComponent("ecoreset", EconomyResetButton)
+}
+
+// economyReset asks the admin to confirm before resetting every balance in the guild
+func economyReset(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	settings := GuildSettings(b, m.GuildID)
+	issuedAt := strconv.FormatInt(time.Now().Unix(), 10)
+
+	_, err := s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("⚠️ This resets every balance in this server to %s and clears work cooldowns. "+
+			"The changes are recorded in the ledger but can't be undone automatically. Continue?", settings.Format(settings.StartingBalance)),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Reset economy",
+						Style:    discordgo.DangerButton,
+						CustomID: commands.CustomID("ecoreset", "confirm", m.Author.ID, issuedAt),
+					},
+					discordgo.Button{
+						Label:    "Cancel",
+						Style:    discordgo.SecondaryButton,
+						CustomID: commands.CustomID("ecoreset", "cancel", m.Author.ID, issuedAt),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Error sending reset confirmation: %v", err)
+	}
+}
+
+// EconomyResetButton handles the confirm and cancel buttons of .economy reset.
+// args are the action, the admin who started the reset and when it was started.
+func EconomyResetButton(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) < 3 {
+		return
+	}
+	action, adminID := args[0], args[1]
+
+	// A malformed timestamp reads as long expired
+	issuedAt, _ := strconv.ParseInt(args[2], 10, 64)
+	prompt := confirmation{
+		adminID:   adminID,
+		issuedAt:  time.Unix(issuedAt, 0),
+		timeout:   resetConfirmTimeout,
+		expired:   "This reset request has expired. Run `.economy reset` again.",
+		cancelled: "Economy reset cancelled.",
+	}
+	if !prompt.accept(s, i, action) {
+		return
+	}
+	userID := utils.InteractionUserID(i)
+
+	changed, err := b.Economy.ResetGuild(i.GuildID, userID)
+	if err != nil {
+		log.Printf("Error resetting economy for guild %s: %v", i.GuildID, err)
+		editConfirmMessage(s, i, "An error occurred while resetting the economy. Nothing was changed.", nil, false)
+		return
+	}
+	topBalances.invalidate(i.GuildID)
+
+	editConfirmMessage(s, i, fmt.Sprintf("Economy reset by <@%s>. %d balances were changed.", userID, changed), nil, true)
+}
@@ -0,0 +1,170 @@
+package economy
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const roleIncomeUsage = "Usage:\n" +
+	"`.roleincome` - list roles that earn passive income\n" +
+	"Admins: `.roleincome set <role> <amount> <hourly|daily>`, `.roleincome remove <role>`"
+
+func init() {
+	commands.RegisterCommand("roleincome", RoleIncome)
+}
+
+// RoleIncome lists the roles that earn passive income and lets admins configure them
+func RoleIncome(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	if len(args) < 2 {
+		listRoleIncome(b, s, m)
+		return
+	}
+
+	action := strings.ToLower(args[1])
+	if action != "set" && action != "remove" {
+		s.ChannelMessageSend(m.ChannelID, roleIncomeUsage)
+		return
+	}
+
+	// Check if the user has administrator permissions
+	hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking admin status: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if !hasAdmin {
+		s.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command.")
+		return
+	}
+
+	if action == "remove" {
+		if len(args) < 3 {
+			s.ChannelMessageSend(m.ChannelID, roleIncomeUsage)
+			return
+		}
+		removeRoleIncome(b, s, m, strings.Join(args[2:], " "))
+		return
+	}
+
+	// The role name may contain spaces, the amount and period are always last
+	if len(args) < 5 {
+		s.ChannelMessageSend(m.ChannelID, roleIncomeUsage)
+		return
+	}
+	period := strings.ToLower(args[len(args)-1])
+	if period != utils.IncomeHourly && period != utils.IncomeDaily {
+		s.ChannelMessageSend(m.ChannelID, "Period must be `hourly` or `daily`.")
+		return
+	}
+	amount, err := strconv.ParseInt(args[len(args)-2], 10, 64)
+	if err != nil || amount <= 0 {
+		s.ChannelMessageSend(m.ChannelID, "Amount must be a number greater than 0.")
+		return
+	}
+
+	roleInput := strings.Join(args[2:len(args)-2], " ")
+	role, err := utils.FindRole(s, m.GuildID, roleInput)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Role '%s' not found.", roleInput))
+		return
+	}
+
+	// Changing the period starts it fresh so the new schedule isn't paid twice in one period
+	_, err = b.Db.Exec(`
+		INSERT INTO role_income (guild_id, role_id, amount, period, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (guild_id, role_id) DO UPDATE SET
+			amount = EXCLUDED.amount,
+			period = EXCLUDED.period,
+			paid_period = CASE WHEN role_income.period = EXCLUDED.period THEN role_income.paid_period ELSE $6 END
+	`, m.GuildID, role.ID, amount, period, m.Author.ID, utils.IncomePeriodStart(period, time.Now()))
+	if err != nil {
+		log.Printf("Error setting role income: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while setting the role income.")
+		return
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Members with <@&%s> now earn %s %s.", role.ID, settings.Format(amount), period))
+}
+
+func removeRoleIncome(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, roleInput string) {
+	role, err := utils.FindRole(s, m.GuildID, roleInput)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Role '%s' not found.", roleInput))
+		return
+	}
+
+	result, err := b.Db.Exec(`DELETE FROM role_income WHERE guild_id = $1 AND role_id = $2`, m.GuildID, role.ID)
+	if err != nil {
+		log.Printf("Error removing role income: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while removing the role income.")
+		return
+	}
+
+	if removed, _ := result.RowsAffected(); removed == 0 {
+		s.ChannelMessageSend(m.ChannelID, "That role doesn't earn passive income.")
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("<@&%s> no longer earns passive income.", role.ID))
+}
+
+func listRoleIncome(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate) {
+	rows, err := b.Db.Query(`
+		SELECT role_id, amount, period
+		FROM role_income
+		WHERE guild_id = $1
+		ORDER BY period, amount DESC
+	`, m.GuildID)
+	if err != nil {
+		log.Printf("Error querying role income: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving role income.")
+		return
+	}
+	defer rows.Close()
+
+	settings := GuildSettings(b, m.GuildID)
+	var lines []string
+	for rows.Next() {
+		var roleID, period string
+		var amount int64
+		if err := rows.Scan(&roleID, &amount, &period); err != nil {
+			log.Printf("Error scanning role income: %v", err)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("<@&%s>: %s %s", roleID, settings.Format(amount), period))
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating role income: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving role income.")
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "Role Income",
+		Description: strings.Join(lines, "\n"),
+		Color:       0x00ff00,
+		Footer:      &discordgo.MessageEmbedFooter{Text: "Paid to members holding the role at the start of each hour or day (UTC)"},
+	}
+	if len(lines) == 0 {
+		embed.Description = "No roles earn passive income in this server."
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, embed)
+}
@@ -0,0 +1,444 @@
+package economy
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// The end-season buttons stop working after this long
+	seasonConfirmTimeout = time.Minute
+
+	maxSeasonTopN     = 25
+	seasonHistorySize = 10
+)
+
+const seasonUsage = "Usage:\n" +
+	"`.season` - show the current season\n" +
+	"`.season history [number]` - show past winners, or a season's final standings\n" +
+	"Admins: `.season end`, `.season top <n>`, `.season role <role|none>`, `.season mode reset|scale <percent>`"
+
+var seasonMedals = []string{"🥇", "🥈", "🥉"}
+
+func init() {
+	commands.RegisterCommand("season", SeasonCommand)
+	commands.RegisterComponent("season", SeasonEndButton)
+}
+
+// SeasonCommand shows the current and past seasons and lets admins configure and close them
+func SeasonCommand(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	if len(args) < 2 {
+		showSeason(b, s, m)
+		return
+	}
+
+	switch strings.ToLower(args[1]) {
+	case "history":
+		seasonHistory(b, s, m, args[2:])
+	case "end", "top", "role", "mode":
+		// Check if the user has administrator permissions
+		hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+		if err != nil {
+			log.Printf("Error checking admin status: %v", err)
+			s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+			return
+		}
+
+		if !hasAdmin {
+			s.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command.")
+			return
+		}
+
+		if strings.ToLower(args[1]) == "end" {
+			confirmSeasonEnd(b, s, m)
+		} else {
+			configureSeason(b, s, m, args[1:])
+		}
+	default:
+		s.ChannelMessageSend(m.ChannelID, seasonUsage)
+	}
+}
+
+// describeSeasonEnd explains what closing the season does with the current settings
+func describeSeasonEnd(config *utils.SeasonConfig, settings *utils.EconomySettings) string {
+	text := fmt.Sprintf("every wallet is reset to %s and banks are emptied", settings.Format(settings.StartingBalance))
+	if config.Mode == utils.SeasonScale {
+		text = fmt.Sprintf("every member keeps %d%% of their wallet and bank", config.ScalePercent)
+	}
+	if config.RoleID != "" {
+		text = fmt.Sprintf("the top %d receive <@&%s> and %s", config.TopN, config.RoleID, text)
+	}
+	return text
+}
+
+func showSeason(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate) {
+	config, err := utils.LoadSeasonConfig(b.Db, m.GuildID)
+	if err != nil {
+		log.Printf("Error loading season config for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+	s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Season %d", config.Number),
+		Description: fmt.Sprintf("Started <t:%d:R>. When an admin ends the season, the leaderboard is archived, %s.", config.StartedAt.Unix(), describeSeasonEnd(config, settings)),
+		Color:       0x00ff00,
+		Footer:      &discordgo.MessageEmbedFooter{Text: "See past winners with .season history"},
+	})
+}
+
+func seasonHistory(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	settings := GuildSettings(b, m.GuildID)
+	if len(args) > 0 {
+		number, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Usage: .season history [number]")
+			return
+		}
+		seasonStandings(b, s, m, settings, number)
+		return
+	}
+
+	rows, err := b.Db.Query(`
+		SELECT s.number, s.ended_at, s.members, ss.rank, ss.user_id, ss.balance
+		FROM (
+			SELECT * FROM seasons WHERE guild_id = $1 ORDER BY number DESC LIMIT $2
+		) s
+		JOIN season_standings ss ON ss.season_id = s.season_id AND ss.rank <= 3
+		ORDER BY s.number DESC, ss.rank
+	`, m.GuildID, seasonHistorySize)
+	if err != nil {
+		log.Printf("Error querying season history: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving the season history.")
+		return
+	}
+	defer rows.Close()
+
+	embed := &discordgo.MessageEmbed{
+		Title:  "Season History",
+		Color:  0xffd700,
+		Footer: &discordgo.MessageEmbedFooter{Text: "Show a season's final standings with .season history <number>"},
+	}
+	var field *discordgo.MessageEmbedField
+	lastNumber := 0
+	for rows.Next() {
+		var number, members, rank int
+		var endedAt time.Time
+		var userID string
+		var balance int64
+		if err := rows.Scan(&number, &endedAt, &members, &rank, &userID, &balance); err != nil {
+			log.Printf("Error scanning season history: %v", err)
+			continue
+		}
+		if number != lastNumber {
+			field = &discordgo.MessageEmbedField{Name: fmt.Sprintf("Season %d • ended <t:%d:D> • %d members", number, endedAt.Unix(), members)}
+			embed.Fields = append(embed.Fields, field)
+			lastNumber = number
+		}
+		field.Value += fmt.Sprintf("%s <@%s> %s\n", seasonMedals[rank-1], userID, settings.Format(balance))
+	}
+
+	if len(embed.Fields) == 0 {
+		embed.Description = "No season has ended in this server yet."
+	}
+	s.ChannelMessageSendEmbed(m.ChannelID, embed)
+}
+
+// seasonStandings shows the top of a past season's archived leaderboard
+func seasonStandings(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, settings *utils.EconomySettings, number int) {
+	var season utils.Season
+	err := b.Db.QueryRow(`
+		SELECT season_id, started_at, ended_at, reset_mode, scale_percent, members
+		FROM seasons
+		WHERE guild_id = $1 AND number = $2
+	`, m.GuildID, number).Scan(&season.ID, &season.StartedAt, &season.EndedAt, &season.Mode, &season.ScalePercent, &season.Members)
+	if err == sql.ErrNoRows {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Season %d hasn't ended yet or doesn't exist.", number))
+		return
+	} else if err != nil {
+		log.Printf("Error querying season: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving the season.")
+		return
+	}
+
+	rows, err := b.Db.Query(`
+		SELECT rank, user_id, balance, bank_balance
+		FROM season_standings
+		WHERE season_id = $1
+		ORDER BY rank
+		LIMIT $2
+	`, season.ID, leaderboardPerPage)
+	if err != nil {
+		log.Printf("Error querying season standings: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving the season.")
+		return
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var standing utils.SeasonStanding
+		if err := rows.Scan(&standing.Rank, &standing.UserID, &standing.Balance, &standing.Bank); err != nil {
+			log.Printf("Error scanning season standing: %v", err)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%d. <@%s> %s (bank %s)", standing.Rank, standing.UserID,
+			settings.Format(standing.Balance), settings.Format(standing.Bank)))
+	}
+
+	ending := "balances were reset"
+	if season.Mode == utils.SeasonScale {
+		ending = fmt.Sprintf("balances were scaled to %d%%", season.ScalePercent)
+	}
+	s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Season %d Final Standings", number),
+		Description: strings.Join(lines, "\n"),
+		Color:       0xffd700,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("%s to %s • %d members • %s", season.StartedAt.Format("Jan 2, 2006"), season.EndedAt.Format("Jan 2, 2006"), season.Members, ending),
+		},
+	})
+}
+
+// configureSeason handles the admin settings subcommands
+func configureSeason(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	var query, reply string
+	var values []interface{}
+	switch strings.ToLower(args[0]) {
+	case "top":
+		n := 0
+		if len(args) > 1 {
+			n, _ = strconv.Atoi(args[1])
+		}
+		if n < 1 || n > maxSeasonTopN {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Usage: .season top <n>, between 1 and %d", maxSeasonTopN))
+			return
+		}
+		query, values = `UPDATE guilds SET season_top_n = $1 WHERE guild_id = $2`, []interface{}{n}
+		reply = fmt.Sprintf("The top %d members will receive the season role.", n)
+
+	case "role":
+		if len(args) < 2 {
+			s.ChannelMessageSend(m.ChannelID, "Usage: .season role <role|none>")
+			return
+		}
+		roleInput := strings.Join(args[1:], " ")
+		if strings.EqualFold(roleInput, "none") {
+			query, values = `UPDATE guilds SET season_role_id = NULL WHERE guild_id = $1`, nil
+			reply = "No role will be awarded when the season ends."
+			break
+		}
+		role, err := utils.FindRole(s, m.GuildID, roleInput)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Role '%s' not found.", roleInput))
+			return
+		}
+		query, values = `UPDATE guilds SET season_role_id = $1 WHERE guild_id = $2`, []interface{}{role.ID}
+		reply = fmt.Sprintf("The season's top members will receive <@&%s>. Last season's holders lose it.", role.ID)
+
+	case "mode":
+		mode := ""
+		if len(args) > 1 {
+			mode = strings.ToLower(args[1])
+		}
+		switch mode {
+		case utils.SeasonReset:
+			query, values = `UPDATE guilds SET season_reset_mode = $1 WHERE guild_id = $2`, []interface{}{mode}
+			reply = "Ending a season will reset every balance to the starting balance."
+		case utils.SeasonScale:
+			percent := 0
+			if len(args) > 2 {
+				percent, _ = strconv.Atoi(strings.TrimSuffix(args[2], "%"))
+			}
+			if percent < 1 || percent > 99 {
+				s.ChannelMessageSend(m.ChannelID, "Usage: .season mode scale <percent>, between 1 and 99")
+				return
+			}
+			query, values = `UPDATE guilds SET season_reset_mode = $1, season_scale_percent = $2 WHERE guild_id = $3`, []interface{}{mode, percent}
+			reply = fmt.Sprintf("Ending a season will keep %d%% of every wallet and bank.", percent)
+		default:
+			s.ChannelMessageSend(m.ChannelID, "Usage: .season mode reset|scale <percent>")
+			return
+		}
+	}
+
+	if _, err := b.Db.Exec(query, append(values, m.GuildID)...); err != nil {
+		log.Printf("Error configuring seasons for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "Error updating the season settings. Please try again later.")
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, reply)
+}
+
+// confirmSeasonEnd asks the admin to confirm before closing the season
+func confirmSeasonEnd(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate) {
+	config, err := utils.LoadSeasonConfig(b.Db, m.GuildID)
+	if err != nil {
+		log.Printf("Error loading season config for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+	issuedAt := strconv.FormatInt(time.Now().Unix(), 10)
+	number := strconv.Itoa(config.Number)
+	_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("⚠️ This ends season %d: the leaderboard is archived, %s. "+
+			"The changes are recorded in the ledger but can't be undone automatically. Continue?", config.Number, describeSeasonEnd(config, settings)),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "End season",
+						Style:    discordgo.DangerButton,
+						CustomID: commands.CustomID("season", "confirm", m.Author.ID, issuedAt, number),
+					},
+					discordgo.Button{
+						Label:    "Cancel",
+						Style:    discordgo.SecondaryButton,
+						CustomID: commands.CustomID("season", "cancel", m.Author.ID, issuedAt, number),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Error sending season confirmation: %v", err)
+	}
+}
+
+// SeasonEndButton handles the confirm and cancel buttons of .season end.
+// args are the action, the admin who asked, when they asked and the season to end.
+func SeasonEndButton(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) < 4 {
+		return
+	}
+	action, adminID := args[0], args[1]
+	number, err := strconv.Atoi(args[3])
+	if err != nil {
+		return
+	}
+
+	// A malformed timestamp reads as long expired
+	issuedAt, _ := strconv.ParseInt(args[2], 10, 64)
+	prompt := confirmation{
+		adminID:   adminID,
+		issuedAt:  time.Unix(issuedAt, 0),
+		timeout:   seasonConfirmTimeout,
+		expired:   "This request has expired. Run `.season end` again.",
+		cancelled: "The season continues.",
+	}
+	if !prompt.accept(s, i, action) {
+		return
+	}
+	userID := utils.InteractionUserID(i)
+
+	season, standings, err := b.Economy.EndSeason(i.GuildID, userID, number)
+	if errors.Is(err, utils.ErrSeasonEnded) {
+		editConfirmMessage(s, i, fmt.Sprintf("Season %d was already ended.", number), nil, true)
+		return
+	} else if errors.Is(err, utils.ErrEmptySeason) {
+		editConfirmMessage(s, i, "There are no members with balances to rank yet.", nil, true)
+		return
+	} else if err != nil {
+		log.Printf("Error ending season for guild %s: %v", i.GuildID, err)
+		// Keep the buttons so the admin can try again
+		editConfirmMessage(s, i, "An error occurred while ending the season. Nothing was changed.", nil, false)
+		return
+	}
+	topBalances.invalidate(i.GuildID)
+
+	settings := GuildSettings(b, i.GuildID)
+	top := standings
+	if len(top) > season.TopN {
+		top = top[:season.TopN]
+	}
+	var winners []string
+	var lines []string
+	for _, standing := range top {
+		winners = append(winners, standing.UserID)
+		place := fmt.Sprintf("%d.", standing.Rank)
+		if standing.Rank <= len(seasonMedals) {
+			place = seasonMedals[standing.Rank-1]
+		}
+		lines = append(lines, fmt.Sprintf("%s <@%s> %s", place, standing.UserID, settings.Format(standing.Balance)))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("🏆 Season %d Champions", season.Number),
+		Description: strings.Join(lines, "\n"),
+		Color:       0xffd700,
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("%d members ranked • Season %d starts now", season.Members, season.Number+1)},
+	}
+	editConfirmMessage(s, i, fmt.Sprintf("Season %d ended by <@%s>.", season.Number, userID), embed, true)
+
+	// Role changes are rate limited, so they happen after the interaction is answered
+	if season.RoleID != "" {
+		added, removed, failed := awardSeasonRole(s, i.GuildID, season.RoleID, winners)
+		reply := fmt.Sprintf("<@&%s> was given to %d members and removed from %d.", season.RoleID, added, removed)
+		if failed > 0 {
+			reply += fmt.Sprintf(" %d changes failed, check the bot's role position.", failed)
+		}
+		s.ChannelMessageSendComplex(i.ChannelID, &discordgo.MessageSend{
+			Content:         reply,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+	}
+}
+
+// awardSeasonRole gives the role to the winners and takes it from last season's holders
+func awardSeasonRole(s *discordgo.Session, guildID, roleID string, winners []string) (added, removed, failed int) {
+	isWinner := make(map[string]bool, len(winners))
+	for _, userID := range winners {
+		isWinner[userID] = true
+	}
+
+	holders, err := utils.RoleHolders(s, guildID)
+	if err != nil {
+		log.Printf("Error fetching season role holders in guild %s: %v", guildID, err)
+	}
+	hasRole := make(map[string]bool)
+	for _, userID := range holders[roleID] {
+		hasRole[userID] = true
+		if isWinner[userID] {
+			continue
+		}
+		if err := s.GuildMemberRoleRemove(guildID, userID, roleID); err != nil {
+			log.Printf("Error removing season role from %s: %v", userID, err)
+			failed++
+			continue
+		}
+		removed++
+	}
+
+	for _, userID := range winners {
+		if hasRole[userID] {
+			continue
+		}
+		if err := s.GuildMemberRoleAdd(guildID, userID, roleID); err != nil {
+			// Winners may have left the server
+			log.Printf("Error adding season role to %s: %v", userID, err)
+			failed++
+			continue
+		}
+		added++
+	}
+	return added, removed, failed
+}
@@ -0,0 +1,352 @@
+package economy
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+// parsePercent parses a percentage such as "2.5" or "2.5%". NaN is rejected because
+// it passes every range check and the >= 0 column constraints.
+func parsePercent(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(percent) {
+		return 0, errors.New("not a number")
+	}
+	return percent, nil
+}
+
+// economySubcommand handles ".economy <name> ..."; args[0] is the subcommand name
+type economySubcommand func(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string)
+
+// economySubcommands holds the admin subcommands of .economy
+var economySubcommands = map[string]economySubcommand{
+	"config": economyConfig,
+}
+
+// economySetting maps a .economy config key to its guilds column
+type economySetting struct {
+	column string
+	apply  func(settings *utils.EconomySettings, value string) error
+}
+
+var economySettings = map[string]economySetting{
+	"currency": {"currency_name", func(es *utils.EconomySettings, value string) error {
+		if len(value) > 32 {
+			return errors.New("Currency name must be 32 characters or fewer.")
+		}
+		es.CurrencyName = value
+		return nil
+	}},
+	"emoji": {"currency_emoji", func(es *utils.EconomySettings, value string) error {
+		if strings.EqualFold(value, "none") {
+			value = ""
+		}
+		if len(value) > 64 {
+			return errors.New("Emoji is too long.")
+		}
+		es.CurrencyEmoji = value
+		return nil
+	}},
+	"starting": {"starting_balance", func(es *utils.EconomySettings, value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return errors.New("Starting balance must be 0 or more.")
+		}
+		es.StartingBalance = n
+		return nil
+	}},
+	"workmin": {"work_min_payout", func(es *utils.EconomySettings, value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return errors.New("Minimum work payout must be 0 or more.")
+		}
+		es.WorkMinPayout = n
+		return nil
+	}},
+	"workmax": {"work_max_payout", func(es *utils.EconomySettings, value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return errors.New("Maximum work payout must be 0 or more.")
+		}
+		es.WorkMaxPayout = n
+		return nil
+	}},
+	"cooldown": {"work_cooldown_hours", func(es *utils.EconomySettings, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > 168 {
+			return errors.New("Work cooldown must be between 0 and 168 hours.")
+		}
+		es.WorkCooldownHours = n
+		return nil
+	}},
+	"interest": {"bank_interest_rate", func(es *utils.EconomySettings, value string) error {
+		rate, err := parsePercent(value)
+		if err != nil || rate < 0 || rate > 10 {
+			return errors.New("Interest rate must be between 0 and 10 percent per day.")
+		}
+		es.BankInterestRate = rate
+		return nil
+	}},
+	"interestcap": {"bank_interest_cap", func(es *utils.EconomySettings, value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return errors.New("Interest cap must be 0 (no limit) or more.")
+		}
+		es.BankInterestCap = n
+		return nil
+	}},
+	"streakbonus": {"streak_bonus", func(es *utils.EconomySettings, value string) error {
+		percent, err := parsePercent(value)
+		if err != nil || percent < 0 || percent > 100 {
+			return errors.New("Streak bonus must be between 0 and 100 percent per day.")
+		}
+		es.StreakBonus = percent
+		return nil
+	}},
+	"streakmax": {"streak_max_bonus", func(es *utils.EconomySettings, value string) error {
+		percent, err := parsePercent(value)
+		if err != nil || percent < 0 || percent > 1000 {
+			return errors.New("Maximum streak bonus must be between 0 and 1000 percent.")
+		}
+		es.StreakMaxBonus = percent
+		return nil
+	}},
+	"streakgrace": {"streak_grace_hours", func(es *utils.EconomySettings, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > 168 {
+			return errors.New("Streak grace period must be between 0 and 168 hours.")
+		}
+		es.StreakGraceHours = n
+		return nil
+	}},
+	"tax": {"transfer_tax", func(es *utils.EconomySettings, value string) error {
+		percent, err := parsePercent(value)
+		if err != nil || percent < 0 || percent > 50 {
+			return errors.New("Transfer tax must be between 0 and 50 percent.")
+		}
+		es.TransferTax = percent
+		return nil
+	}},
+	"dailycap": {"transfer_daily_cap", func(es *utils.EconomySettings, value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return errors.New("Daily transfer cap must be 0 (no limit) or more.")
+		}
+		es.TransferDailyCap = n
+		return nil
+	}},
+	"memberdays": {"transfer_min_member_days", func(es *utils.EconomySettings, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > 365 {
+			return errors.New("Minimum membership age must be between 0 and 365 days.")
+		}
+		es.TransferMinMemberDays = n
+		return nil
+	}},
+	"accountdays": {"transfer_min_account_days", func(es *utils.EconomySettings, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > 365 {
+			return errors.New("Minimum account age must be between 0 and 365 days.")
+		}
+		es.TransferMinAccountDays = n
+		return nil
+	}},
+	"confirmabove": {"transfer_confirm_above", func(es *utils.EconomySettings, value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return errors.New("Confirmation threshold must be 0 (never ask) or more.")
+		}
+		es.TransferConfirmAbove = n
+		return nil
+	}},
+	"maxbet": {"flip_max_bet", func(es *utils.EconomySettings, value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return errors.New("Maximum bet must be 0 (no limit) or more.")
+		}
+		es.FlipMaxBet = n
+		return nil
+	}},
+}
+
+func init() {
+	commands.RegisterCommand("economy", Economy, "eco")
+}
+
+// GuildSettings returns the guild's economy settings, or the defaults if they
+// can't be loaded
+func GuildSettings(b *bot.Bot, guildID string) *utils.EconomySettings {
+	settings, err := b.Economy.Settings(guildID)
+	if err != nil {
+		log.Printf("Error loading economy settings for guild %s: %v", guildID, err)
+		return utils.DefaultEconomySettings()
+	}
+	return settings
+}
+
+// Economy dispatches the admin economy subcommands
+func Economy(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	// Check if the user has administrator permissions
+	hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking admin status: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if !hasAdmin {
+		s.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command.")
+		return
+	}
+
+	names := make([]string, 0, len(economySubcommands))
+	for name := range economySubcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	usage := fmt.Sprintf("Usage: .economy <%s>", strings.Join(names, "|"))
+
+	if len(args) < 2 {
+		s.ChannelMessageSend(m.ChannelID, usage)
+		return
+	}
+
+	subcommand, ok := economySubcommands[strings.ToLower(args[1])]
+	if !ok {
+		s.ChannelMessageSend(m.ChannelID, usage)
+		return
+	}
+	subcommand(b, s, m, args[1:])
+}
+
+// economyConfig shows the guild's economy settings or changes one of them
+func economyConfig(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	settings, err := b.Economy.Settings(m.GuildID)
+	if err != nil {
+		log.Printf("Error loading economy settings for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if len(args) < 3 {
+		s.ChannelMessageSendEmbed(m.ChannelID, economySettingsEmbed(settings))
+		return
+	}
+
+	key := strings.ToLower(args[1])
+	setting, ok := economySettings[key]
+	if !ok {
+		keys := make([]string, 0, len(economySettings))
+		for name := range economySettings {
+			keys = append(keys, name)
+		}
+		sort.Strings(keys)
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Unknown setting. Use one of: %s.", strings.Join(keys, ", ")))
+		return
+	}
+
+	value := strings.Join(args[2:], " ")
+	if err := setting.apply(settings, value); err != nil {
+		s.ChannelMessageSend(m.ChannelID, err.Error())
+		return
+	}
+	if settings.WorkMinPayout > settings.WorkMaxPayout {
+		s.ChannelMessageSend(m.ChannelID, "The minimum work payout can't be higher than the maximum.")
+		return
+	}
+
+	stored := map[string]interface{}{
+		"currency_name":             settings.CurrencyName,
+		"currency_emoji":            settings.CurrencyEmoji,
+		"starting_balance":          settings.StartingBalance,
+		"work_min_payout":           settings.WorkMinPayout,
+		"work_max_payout":           settings.WorkMaxPayout,
+		"work_cooldown_hours":       settings.WorkCooldownHours,
+		"flip_max_bet":              settings.FlipMaxBet,
+		"bank_interest_rate":        settings.BankInterestRate,
+		"bank_interest_cap":         settings.BankInterestCap,
+		"streak_bonus":              settings.StreakBonus,
+		"streak_max_bonus":          settings.StreakMaxBonus,
+		"streak_grace_hours":        settings.StreakGraceHours,
+		"transfer_tax":              settings.TransferTax,
+		"transfer_daily_cap":        settings.TransferDailyCap,
+		"transfer_min_member_days":  settings.TransferMinMemberDays,
+		"transfer_min_account_days": settings.TransferMinAccountDays,
+		"transfer_confirm_above":    settings.TransferConfirmAbove,
+	}[setting.column]
+
+	// The column comes from economySettings, never from user input
+	_, err = b.Db.Exec(fmt.Sprintf("UPDATE guilds SET %s = $1 WHERE guild_id = $2", setting.column), stored, m.GuildID)
+	if err != nil {
+		log.Printf("Error updating %s for guild %s: %v", setting.column, m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "Error updating the setting. Please try again later.")
+		return
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, economySettingsEmbed(settings))
+}
+
+// economySettingsEmbed lists the settings along with the keys used to change them
+func economySettingsEmbed(settings *utils.EconomySettings) *discordgo.MessageEmbed {
+	emoji := settings.CurrencyEmoji
+	if emoji == "" {
+		emoji = "None"
+	}
+	maxBet := "No limit"
+	if settings.FlipMaxBet > 0 {
+		maxBet = settings.Format(settings.FlipMaxBet)
+	}
+	interestCap := "No limit"
+	if settings.BankInterestCap > 0 {
+		interestCap = settings.Format(settings.BankInterestCap)
+	}
+	dailyCap := "No limit"
+	if settings.TransferDailyCap > 0 {
+		dailyCap = settings.Format(settings.TransferDailyCap)
+	}
+	confirmAbove := "Never"
+	if settings.TransferConfirmAbove > 0 {
+		confirmAbove = settings.Format(settings.TransferConfirmAbove)
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       "Economy Settings",
+		Description: "Change a setting with `.economy config <setting> <value>`",
+		Color:       0x00ff00,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Currency (`currency`)", Value: settings.CurrencyName, Inline: true},
+			{Name: "Emoji (`emoji`)", Value: emoji, Inline: true},
+			{Name: "Starting Balance (`starting`)", Value: settings.Format(settings.StartingBalance), Inline: true},
+			{Name: "Work Payout (`workmin`/`workmax`)", Value: fmt.Sprintf("%d - %d", settings.WorkMinPayout, settings.WorkMaxPayout), Inline: true},
+			{Name: "Work Cooldown (`cooldown`)", Value: fmt.Sprintf("%d hours", settings.WorkCooldownHours), Inline: true},
+			{Name: "Flip Max Bet (`maxbet`)", Value: maxBet, Inline: true},
+			{Name: "Bank Interest (`interest`)", Value: fmt.Sprintf("%g%% per day", settings.BankInterestRate), Inline: true},
+			{Name: "Interest Cap (`interestcap`)", Value: interestCap, Inline: true},
+			{Name: "Streak Bonus (`streakbonus`/`streakmax`)", Value: fmt.Sprintf("+%g%% per day, up to +%g%%", settings.StreakBonus, settings.StreakMaxBonus), Inline: true},
+			{Name: "Streak Grace (`streakgrace`)", Value: fmt.Sprintf("%d hours", settings.StreakGraceHours), Inline: true},
+			{Name: "Transfer Tax (`tax`)", Value: fmt.Sprintf("%g%%", settings.TransferTax), Inline: true},
+			{Name: "Daily Transfer Cap (`dailycap`)", Value: dailyCap, Inline: true},
+			{Name: "Confirm Transfers From (`confirmabove`)", Value: confirmAbove, Inline: true},
+			{Name: "Min. Membership Age (`memberdays`)", Value: fmt.Sprintf("%d days", settings.TransferMinMemberDays), Inline: true},
+			{Name: "Min. Account Age (`accountdays`)", Value: fmt.Sprintf("%d days", settings.TransferMinAccountDays), Inline: true},
+		},
+	}
+}
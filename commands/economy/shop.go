@@ -0,0 +1,405 @@
+package economy
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/commands/roles"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	shopItemsPerPage  = 10
+	inventoryPerPage  = 10
+	maxInventoryItems = 250
+)
+
+var (
+	errItemNotFound = errors.New("item not found")
+	errOutOfStock   = errors.New("item out of stock")
+)
+
+func init() {
+	commands.RegisterCommand("shop", Shop)
+	commands.RegisterCommand("buy", Buy)
+	commands.RegisterCommand("inventory", Inventory, "inv")
+}
+
+// shopItem is a row of shop_items
+type shopItem struct {
+	ID          int64
+	Name        string
+	Description string
+	Price       int64
+	Stock       sql.NullInt64 // invalid means unlimited
+	RoleID      string        // empty when the item grants no role
+	Duration    time.Duration // 0 for permanent items
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const shopItemColumns = `item_id, name, COALESCE(description, ''), price, stock, COALESCE(role_id::text, ''), COALESCE(duration_seconds, 0)`
+
+func scanShopItem(row rowScanner) (*shopItem, error) {
+	item := &shopItem{}
+	var durationSeconds int64
+	err := row.Scan(&item.ID, &item.Name, &item.Description, &item.Price, &item.Stock, &item.RoleID, &durationSeconds)
+	if err != nil {
+		return nil, err
+	}
+	item.Duration = time.Duration(durationSeconds) * time.Second
+	return item, nil
+}
+
+// findShopItem looks an active item up by "#id", id or name. With a transaction the
+// item row stays locked until it commits.
+func findShopItem(db *sql.DB, tx *sql.Tx, guildID, query string) (*shopItem, error) {
+	sqlQuery := `SELECT ` + shopItemColumns + ` FROM shop_items WHERE guild_id = $1 AND active AND `
+	var arg interface{}
+	if id, err := strconv.ParseInt(strings.TrimPrefix(query, "#"), 10, 64); err == nil {
+		sqlQuery += `item_id = $2`
+		arg = id
+	} else {
+		sqlQuery += `lower(name) = lower($2)`
+		arg = query
+	}
+
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRow(sqlQuery+` FOR UPDATE`, guildID, arg)
+	} else {
+		row = db.QueryRow(sqlQuery, guildID, arg)
+	}
+
+	item, err := scanShopItem(row)
+	if err == sql.ErrNoRows {
+		return nil, errItemNotFound
+	}
+	return item, err
+}
+
+// describe renders the item's details for shop listings
+func (item *shopItem) describe(settings *utils.EconomySettings) string {
+	details := []string{settings.Format(item.Price)}
+	if item.Stock.Valid {
+		details = append(details, fmt.Sprintf("%d in stock", item.Stock.Int64))
+	}
+	if item.RoleID != "" {
+		details = append(details, fmt.Sprintf("grants <@&%s>", item.RoleID))
+	}
+	if item.Duration > 0 {
+		details = append(details, fmt.Sprintf("lasts %s", commands.FormatDuration(item.Duration)))
+	}
+
+	text := strings.Join(details, " • ")
+	if item.Description != "" {
+		text = item.Description + "\n" + text
+	}
+	return text
+}
+
+// Shop lists the items for sale in the guild
+func Shop(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	rows, err := b.Db.Query(`SELECT `+shopItemColumns+` FROM shop_items WHERE guild_id = $1 AND active ORDER BY price, item_id`, m.GuildID)
+	if err != nil {
+		log.Printf("Error querying shop items: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving the shop.")
+		return
+	}
+	defer rows.Close()
+
+	var items []*shopItem
+	for rows.Next() {
+		item, err := scanShopItem(rows)
+		if err != nil {
+			log.Printf("Error scanning shop item: %v", err)
+			continue
+		}
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "The shop is empty.")
+		return
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+
+	var pages []*discordgo.MessageEmbed
+	for start := 0; start < len(items); start += shopItemsPerPage {
+		end := start + shopItemsPerPage
+		if end > len(items) {
+			end = len(items)
+		}
+
+		page := &discordgo.MessageEmbed{
+			Title:       "Shop",
+			Description: "Buy an item with `.buy <item name or #id>`",
+			Color:       0x00ff00,
+		}
+		for _, item := range items[start:end] {
+			page.Fields = append(page.Fields, &discordgo.MessageEmbedField{
+				Name:  fmt.Sprintf("#%d %s", item.ID, item.Name),
+				Value: item.describe(settings),
+			})
+		}
+		pages = append(pages, page)
+	}
+
+	// Add footer to each page with page number
+	for i, page := range pages {
+		page.Footer = &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Page %d of %d", i+1, len(pages)),
+		}
+	}
+
+	if err := commands.SendPaginated(s, m.ChannelID, m.GuildID, m.Author.ID, pages, 0); err != nil {
+		log.Printf("Error sending shop: %v", err)
+	}
+}
+
+// Buy purchases a shop item, charging the member and granting its role if it has one
+func Buy(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	if len(args) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Usage: .buy <item name or #id>")
+		return
+	}
+	query := strings.Join(args[1:], " ")
+
+	item, err := findShopItem(b.Db, nil, m.GuildID, query)
+	if errors.Is(err, errItemNotFound) {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Item '%s' not found. Use `.shop` to see what's for sale.", query))
+		return
+	} else if err != nil {
+		log.Printf("Error querying shop item: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	// Role items go through the same hierarchy check as .setrole, with the bot as the assigner
+	var role *discordgo.Role
+	if item.RoleID != "" {
+		guild, err := s.Guild(m.GuildID)
+		if err != nil {
+			log.Printf("Error fetching guild: %v", err)
+			s.ChannelMessageSend(m.ChannelID, "An error occurred while fetching server information.")
+			return
+		}
+		for _, r := range guild.Roles {
+			if r.ID == item.RoleID {
+				role = r
+				break
+			}
+		}
+		if role == nil {
+			s.ChannelMessageSend(m.ChannelID, "The role for this item no longer exists. Please ask an admin to update the shop.")
+			return
+		}
+
+		err = roles.CheckAssignable(s, guild, s.State.User.ID, role)
+		if errors.Is(err, roles.ErrRoleTooHigh) {
+			s.ChannelMessageSend(m.ChannelID, "I cannot give out this role because it is higher than my highest role. Please ask an admin.")
+			return
+		} else if err != nil {
+			log.Printf("Error checking role hierarchy: %v", err)
+			s.ChannelMessageSend(m.ChannelID, "An error occurred while verifying permissions.")
+			return
+		}
+	}
+
+	if err := b.Economy.EnsureMember(m.GuildID, m.Author); err != nil {
+		log.Printf("Error ensuring member: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	// Stock, payment and inventory are updated together. The role is added last so
+	// a Discord error rolls the purchase back.
+	roleAdded := false
+	var newBalance int64
+	err = b.Economy.WithTx(func(tx *sql.Tx) error {
+		locked, err := findShopItem(b.Db, tx, m.GuildID, strconv.FormatInt(item.ID, 10))
+		if err != nil {
+			return err
+		}
+		if locked.Stock.Valid && locked.Stock.Int64 <= 0 {
+			return errOutOfStock
+		}
+
+		if _, err := utils.LockBalance(tx, m.GuildID, m.Author.ID); err != nil {
+			return err
+		}
+
+		newBalance, err = utils.ApplyChange(tx, utils.BalanceChange{
+			GuildID: m.GuildID,
+			UserID:  m.Author.ID,
+			Amount:  -locked.Price,
+			Reason:  utils.ReasonShopPurchase,
+			ActorID: m.Author.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE shop_items SET stock = stock - 1 WHERE item_id = $1 AND stock IS NOT NULL`, locked.ID)
+		if err != nil {
+			return err
+		}
+
+		var expiresAt interface{}
+		if locked.Duration > 0 {
+			expiresAt = time.Now().Add(locked.Duration)
+		}
+		_, err = tx.Exec(`
+			INSERT INTO inventory (guild_id, user_id, item_id, price_paid, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`, m.GuildID, m.Author.ID, locked.ID, locked.Price, expiresAt)
+		if err != nil {
+			return err
+		}
+
+		if role != nil {
+			// Timed role items add their duration to any time the member has left
+			if locked.Duration > 0 {
+				_, err = utils.ExtendRoleExpiry(tx, m.GuildID, m.Author.ID, role.ID, locked.Duration, utils.RoleSourceShop)
+			} else {
+				err = utils.ClearRoleExpiry(tx, m.GuildID, m.Author.ID, role.ID)
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := roles.AddRole(s, m.GuildID, m.Author.ID, role); err != nil {
+				return err
+			}
+			roleAdded = true
+		}
+		item = locked
+		return nil
+	})
+
+	if err != nil && roleAdded {
+		// The purchase was rolled back after the role was given
+		if err := s.GuildMemberRoleRemove(m.GuildID, m.Author.ID, role.ID); err != nil {
+			log.Printf("Error removing role after failed purchase: %v", err)
+		}
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+	switch {
+	case errors.Is(err, errItemNotFound):
+		s.ChannelMessageSend(m.ChannelID, "This item was just removed from the shop.")
+	case errors.Is(err, errOutOfStock):
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("'%s' is out of stock.", item.Name))
+	case errors.Is(err, utils.ErrInsufficientFunds):
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Not enough %s. '%s' costs %s.", settings.CurrencyName, item.Name, settings.Format(item.Price)))
+	case errors.Is(err, roles.ErrBotCannotAssign):
+		s.ChannelMessageSend(m.ChannelID, "I cannot add this role because it is higher than my highest role or due to missing permissions.")
+	case err != nil:
+		log.Printf("Error buying shop item: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+	default:
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You bought '%s' for %s. Your new balance is %s.", item.Name, settings.Format(item.Price), settings.Format(newBalance)))
+	}
+}
+
+// Inventory lists the items a member owns that haven't expired
+func Inventory(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	targetUserID := m.Author.ID
+	if len(args) >= 2 {
+		var err error
+		targetUserID, err = utils.ExtractUserID(args[1])
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, "Invalid mention / use. Please use a proper mention (e.g., @username).")
+			return
+		}
+	}
+
+	rows, err := b.Db.Query(`
+		SELECT si.name, inv.purchased_at, inv.expires_at
+		FROM inventory inv
+		JOIN shop_items si ON si.item_id = inv.item_id
+		WHERE inv.guild_id = $1 AND inv.user_id = $2
+			AND (inv.expires_at IS NULL OR inv.expires_at > NOW())
+		ORDER BY inv.purchased_at DESC
+		LIMIT $3
+	`, m.GuildID, targetUserID, maxInventoryItems)
+	if err != nil {
+		log.Printf("Error querying inventory: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving the inventory.")
+		return
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var name string
+		var purchasedAt time.Time
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&name, &purchasedAt, &expiresAt); err != nil {
+			log.Printf("Error scanning inventory item: %v", err)
+			continue
+		}
+
+		line := fmt.Sprintf("**%s** - bought <t:%d:d>", name, purchasedAt.Unix())
+		if expiresAt.Valid {
+			line += fmt.Sprintf(", expires <t:%d:R>", expiresAt.Time.Unix())
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "No items yet. Use `.shop` to see what's for sale.")
+		return
+	}
+
+	var pages []*discordgo.MessageEmbed
+	for start := 0; start < len(lines); start += inventoryPerPage {
+		end := start + inventoryPerPage
+		if end > len(lines) {
+			end = len(lines)
+		}
+		pages = append(pages, &discordgo.MessageEmbed{
+			Title:       "Inventory",
+			Description: fmt.Sprintf("Items owned by <@%s>\n\n%s", targetUserID, strings.Join(lines[start:end], "\n")),
+			Color:       0x00ff00,
+		})
+	}
+
+	// Add footer to each page with page number
+	for i, page := range pages {
+		page.Footer = &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Page %d of %d", i+1, len(pages)),
+		}
+	}
+
+	if err := commands.SendPaginated(s, m.ChannelID, m.GuildID, m.Author.ID, pages, 0); err != nil {
+		log.Printf("Error sending inventory: %v", err)
+	}
+}
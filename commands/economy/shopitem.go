@@ -0,0 +1,249 @@
+package economy
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/commands/roles"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const shopItemUsage = "Usage:\n" +
+	"`.shopitem add <price> <name> [stock:<n>] [role:<@role>] [duration:<7d>] [| description]`\n" +
+	"`.shopitem stock <item> <n|unlimited>`\n" +
+	"`.shopitem remove <item>`"
+
+func init() {
+	commands.RegisterCommand("shopitem", ShopItem)
+}
+
+// ShopItem lets admins manage the guild shop
+func ShopItem(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	// Check if the user has administrator permissions
+	hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking admin status: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if !hasAdmin {
+		s.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command.")
+		return
+	}
+
+	if len(args) < 3 {
+		s.ChannelMessageSend(m.ChannelID, shopItemUsage)
+		return
+	}
+
+	switch strings.ToLower(args[1]) {
+	case "add":
+		addShopItem(b, s, m, args[2:])
+	case "stock":
+		setShopItemStock(b, s, m, args[2:])
+	case "remove", "delete":
+		removeShopItem(b, s, m, strings.Join(args[2:], " "))
+	default:
+		s.ChannelMessageSend(m.ChannelID, shopItemUsage)
+	}
+}
+
+// addShopItem parses "<price> <name> [options] [| description]" and creates the item
+func addShopItem(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	price, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || price <= 0 {
+		s.ChannelMessageSend(m.ChannelID, "Price must be a number greater than 0.\n"+shopItemUsage)
+		return
+	}
+
+	// Everything after "|" is the description
+	rest := strings.Join(args[1:], " ")
+	description := ""
+	if idx := strings.Index(rest, "|"); idx >= 0 {
+		description = strings.TrimSpace(rest[idx+1:])
+		rest = rest[:idx]
+	}
+
+	item := &shopItem{Price: price, Description: description}
+	var nameParts []string
+	for _, word := range strings.Fields(rest) {
+		lower := strings.ToLower(word)
+		switch {
+		case strings.HasPrefix(lower, "stock:"):
+			stock, err := strconv.ParseInt(strings.TrimPrefix(lower, "stock:"), 10, 64)
+			if err != nil || stock < 0 {
+				s.ChannelMessageSend(m.ChannelID, "Stock must be 0 or more.")
+				return
+			}
+			item.Stock.Int64, item.Stock.Valid = stock, true
+		case strings.HasPrefix(lower, "role:"):
+			item.RoleID = utils.ExtractRoleID(word[len("role:"):])
+		case strings.HasPrefix(lower, "duration:"):
+			duration, err := commands.ParseDuration(strings.TrimPrefix(lower, "duration:"))
+			if err != nil || duration < time.Minute {
+				s.ChannelMessageSend(m.ChannelID, "Invalid duration. Use something like `30m`, `12h` or `7d`.")
+				return
+			}
+			item.Duration = duration
+		default:
+			nameParts = append(nameParts, word)
+		}
+	}
+
+	item.Name = strings.Join(nameParts, " ")
+	if item.Name == "" {
+		s.ChannelMessageSend(m.ChannelID, "Please give the item a name.\n"+shopItemUsage)
+		return
+	}
+	if len(item.Name) > 100 {
+		s.ChannelMessageSend(m.ChannelID, "Item names must be 100 characters or fewer.")
+		return
+	}
+	if _, err := strconv.ParseInt(strings.TrimPrefix(item.Name, "#"), 10, 64); err == nil {
+		s.ChannelMessageSend(m.ChannelID, "Item names can't be plain numbers, they are used for item IDs.")
+		return
+	}
+
+	// Only roles the admin could assign with .setrole can be sold
+	if item.RoleID != "" {
+		guild, err := s.Guild(m.GuildID)
+		if err != nil {
+			log.Printf("Error fetching guild: %v", err)
+			s.ChannelMessageSend(m.ChannelID, "An error occurred while fetching server information.")
+			return
+		}
+
+		var role *discordgo.Role
+		for _, r := range guild.Roles {
+			if r.ID == item.RoleID {
+				role = r
+				break
+			}
+		}
+		if role == nil {
+			s.ChannelMessageSend(m.ChannelID, "Role not found. Use a role mention, e.g. `role:@VIP`.")
+			return
+		}
+
+		err = roles.CheckAssignable(s, guild, m.Author.ID, role)
+		if errors.Is(err, roles.ErrRoleTooHigh) {
+			s.ChannelMessageSend(m.ChannelID, "You cannot sell a role equal to or higher than your highest role.")
+			return
+		} else if err != nil {
+			log.Printf("Error checking role hierarchy: %v", err)
+			s.ChannelMessageSend(m.ChannelID, "An error occurred while verifying permissions.")
+			return
+		}
+	}
+
+	if _, err := findShopItem(b.Db, nil, m.GuildID, item.Name); err == nil {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("An item named '%s' already exists.", item.Name))
+		return
+	} else if !errors.Is(err, errItemNotFound) {
+		log.Printf("Error querying shop item: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	var roleID, durationSeconds, stock interface{}
+	if item.RoleID != "" {
+		roleID = item.RoleID
+	}
+	if item.Duration > 0 {
+		durationSeconds = int64(item.Duration.Seconds())
+	}
+	if item.Stock.Valid {
+		stock = item.Stock.Int64
+	}
+
+	err = b.Db.QueryRow(`
+		INSERT INTO shop_items (guild_id, name, description, price, stock, role_id, duration_seconds, created_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8)
+		RETURNING item_id
+	`, m.GuildID, item.Name, item.Description, item.Price, stock, roleID, durationSeconds, m.Author.ID).Scan(&item.ID)
+	if err != nil {
+		log.Printf("Error creating shop item: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while creating the item.")
+		return
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Added #%d %s", item.ID, item.Name),
+		Description: item.describe(GuildSettings(b, m.GuildID)),
+		Color:       0x00ff00,
+	})
+}
+
+// setShopItemStock parses "<item> <n|unlimited>" and updates the item's stock
+func setShopItemStock(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	if len(args) < 2 {
+		s.ChannelMessageSend(m.ChannelID, shopItemUsage)
+		return
+	}
+
+	var stock interface{}
+	last := strings.ToLower(args[len(args)-1])
+	if last != "unlimited" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			s.ChannelMessageSend(m.ChannelID, "Stock must be 0 or more, or `unlimited`.")
+			return
+		}
+		stock = n
+	}
+
+	query := strings.Join(args[:len(args)-1], " ")
+	item, err := findShopItem(b.Db, nil, m.GuildID, query)
+	if errors.Is(err, errItemNotFound) {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Item '%s' not found.", query))
+		return
+	} else if err != nil {
+		log.Printf("Error querying shop item: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	_, err = b.Db.Exec(`UPDATE shop_items SET stock = $1 WHERE item_id = $2`, stock, item.ID)
+	if err != nil {
+		log.Printf("Error updating shop item stock: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Stock for '%s' set to %s.", item.Name, last))
+}
+
+// removeShopItem takes an item off sale. Members keep the copies they already bought.
+func removeShopItem(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, query string) {
+	item, err := findShopItem(b.Db, nil, m.GuildID, query)
+	if errors.Is(err, errItemNotFound) {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Item '%s' not found.", query))
+		return
+	} else if err != nil {
+		log.Printf("Error querying shop item: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	_, err = b.Db.Exec(`UPDATE shop_items SET active = FALSE WHERE item_id = $1`, item.ID)
+	if err != nil {
+		log.Printf("Error removing shop item: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Removed '%s' from the shop.", item.Name))
+}
@@ -0,0 +1,230 @@
+package economy
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	transactionsPerPage = 10
+	// Statements only load the most recent entries matching the filters
+	maxStatementEntries = 500
+)
+
+// Reasons admins can filter the ledger by
+var filterableReasons = map[string]bool{
+	utils.ReasonWork:            true,
+	utils.ReasonFlip:            true,
+	utils.ReasonTransfer:        true,
+	utils.ReasonAdminAdd:        true,
+	utils.ReasonAdminTake:       true,
+	utils.ReasonStartingBalance: true,
+	utils.ReasonAdminSet:        true,
+	utils.ReasonAdminGrant:      true,
+	utils.ReasonAdminReset:      true,
+	utils.ReasonShopPurchase:    true,
+	utils.ReasonBankDeposit:     true,
+	utils.ReasonBankWithdraw:    true,
+	utils.ReasonInterest:        true,
+	utils.ReasonCasinoBet:       true,
+	utils.ReasonCasinoPayout:    true,
+	utils.ReasonEscrowRefund:    true,
+	utils.ReasonDuelStake:       true,
+	utils.ReasonDuelPayout:      true,
+	utils.ReasonTransferTax:     true,
+	utils.ReasonLotteryTicket:   true,
+	utils.ReasonLotteryPrize:    true,
+	utils.ReasonRoleIncome:      true,
+	utils.ReasonAdminImport:     true,
+	utils.ReasonSeasonReset:     true,
+	utils.ReasonVoice:           true,
+}
+
+func init() {
+	commands.RegisterCommand("transactions", Transactions, "tx", "statement")
+}
+
+// statementFilter holds the parsed .transactions arguments
+type statementFilter struct {
+	userID string
+	page   int
+	reason string
+	from   time.Time
+	to     time.Time
+}
+
+// Transactions lists ledger entries for a member with their running balance
+func Transactions(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	filter, err := parseStatementArgs(args[1:])
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%v\nUsage: .transactions [@user] [page] [reason:<reason>] [from:YYYY-MM-DD] [to:YYYY-MM-DD]", err))
+		return
+	}
+	if filter.userID == "" {
+		filter.userID = m.Author.ID
+	}
+
+	// Other members' statements and filters are admin only
+	if filter.userID != m.Author.ID || filter.reason != "" || !filter.from.IsZero() || !filter.to.IsZero() {
+		hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+		if err != nil {
+			log.Printf("Error checking admin status: %v", err)
+			s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+			return
+		}
+		if !hasAdmin {
+			s.ChannelMessageSend(m.ChannelID, "Only admins can view other members' transactions or filter them.")
+			return
+		}
+	}
+
+	query := `
+		SELECT account, amount, balance_after, reason, COALESCE(counterparty_id::text, ''), created_at
+		FROM transactions
+		WHERE guild_id = $1 AND user_id = $2`
+	queryArgs := []interface{}{m.GuildID, filter.userID}
+	if filter.reason != "" {
+		queryArgs = append(queryArgs, filter.reason)
+		query += fmt.Sprintf(" AND reason = $%d", len(queryArgs))
+	}
+	if !filter.from.IsZero() {
+		queryArgs = append(queryArgs, filter.from)
+		query += fmt.Sprintf(" AND created_at >= $%d", len(queryArgs))
+	}
+	if !filter.to.IsZero() {
+		// Include the whole end day
+		queryArgs = append(queryArgs, filter.to.AddDate(0, 0, 1))
+		query += fmt.Sprintf(" AND created_at < $%d", len(queryArgs))
+	}
+	queryArgs = append(queryArgs, maxStatementEntries)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, transaction_id DESC LIMIT $%d", len(queryArgs))
+
+	rows, err := b.Db.Query(query, queryArgs...)
+	if err != nil {
+		log.Printf("Error querying transactions: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving transactions.")
+		return
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var amount, balanceAfter int64
+		var account, reason, counterpartyID string
+		var createdAt time.Time
+		if err := rows.Scan(&account, &amount, &balanceAfter, &reason, &counterpartyID, &createdAt); err != nil {
+			log.Printf("Error scanning transaction: %v", err)
+			continue
+		}
+
+		line := fmt.Sprintf("<t:%d:d> <t:%d:t> `%s` **%+d** → %d", createdAt.Unix(), createdAt.Unix(), reason, amount, balanceAfter)
+		if account == utils.AccountBank {
+			line += " 🏦"
+		}
+		if counterpartyID != "" {
+			line += fmt.Sprintf(" (<@%s>)", counterpartyID)
+		}
+		lines = append(lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating transactions: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving transactions.")
+		return
+	}
+
+	if len(lines) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "No transactions found.")
+		return
+	}
+
+	title := "Transactions"
+	if filter.reason != "" {
+		title += fmt.Sprintf(" - %s", filter.reason)
+	}
+
+	var pages []*discordgo.MessageEmbed
+	for start := 0; start < len(lines); start += transactionsPerPage {
+		end := start + transactionsPerPage
+		if end > len(lines) {
+			end = len(lines)
+		}
+		pages = append(pages, &discordgo.MessageEmbed{
+			Title:       title,
+			Description: fmt.Sprintf("Statement for <@%s>\n\n%s", filter.userID, strings.Join(lines[start:end], "\n")),
+			Color:       0x00ff00,
+		})
+	}
+
+	// Add footer to each page with page number
+	for i, page := range pages {
+		page.Footer = &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Page %d of %d", i+1, len(pages)),
+		}
+	}
+
+	if filter.page > len(pages) {
+		filter.page = len(pages)
+	}
+
+	if err := commands.SendPaginated(s, m.ChannelID, m.GuildID, m.Author.ID, pages, filter.page-1); err != nil {
+		log.Printf("Error sending transactions: %v", err)
+	}
+}
+
+// parseStatementArgs parses "[@user] [page] [reason:x] [from:date] [to:date]" in any order
+func parseStatementArgs(args []string) (*statementFilter, error) {
+	filter := &statementFilter{page: 1}
+
+	for _, arg := range args {
+		lower := strings.ToLower(arg)
+		switch {
+		case strings.HasPrefix(arg, "<@"):
+			userID, err := utils.ExtractUserID(arg)
+			if err != nil {
+				return nil, errors.New("Invalid mention. Please use a proper mention (e.g., @username).")
+			}
+			filter.userID = userID
+		case strings.HasPrefix(lower, "reason:"):
+			reason := strings.TrimPrefix(lower, "reason:")
+			if !filterableReasons[reason] {
+				return nil, errors.New("Invalid reason. Use one of: work, flip, transfer, admin_add, admin_take, admin_set, admin_grant, admin_reset, starting_balance, shop_purchase, bank_deposit, bank_withdraw, interest, casino_bet, casino_payout, escrow_refund, duel_stake, duel_payout, transfer_tax, lottery_ticket, lottery_prize, role_income, admin_import, season_reset, voice.")
+			}
+			filter.reason = reason
+		case strings.HasPrefix(lower, "from:"):
+			date, err := time.Parse("2006-01-02", strings.TrimPrefix(lower, "from:"))
+			if err != nil {
+				return nil, errors.New("Invalid from date. Use YYYY-MM-DD.")
+			}
+			filter.from = date
+		case strings.HasPrefix(lower, "to:"):
+			date, err := time.Parse("2006-01-02", strings.TrimPrefix(lower, "to:"))
+			if err != nil {
+				return nil, errors.New("Invalid to date. Use YYYY-MM-DD.")
+			}
+			filter.to = date
+		default:
+			page, err := strconv.Atoi(arg)
+			if err != nil || page < 1 {
+				return nil, errors.New("Invalid page number.")
+			}
+			filter.page = page
+		}
+	}
+
+	return filter, nil
+}
@@ -2,8 +2,12 @@ package economy
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"strconv"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"DiscordBot/bot"
@@ -11,8 +15,126 @@ import (
 	"DiscordBot/commands"
 )
 
+// The confirmation buttons for large transfers stop working after this long
+const transferConfirmTimeout = time.Minute
+
 func init() {
 	commands.RegisterCommand("transfer", Transfer)
+	commands.RegisterComponent("transfer", TransferButton)
+}
+
+// errTransferClosed is returned when a confirmation was already used or cancelled
+var errTransferClosed = errors.New("transfer already sent or cancelled")
+
+// transferRuleError names the guild rule that blocked a transfer
+type transferRuleError struct {
+	rule    string
+	message string
+}
+
+func (e transferRuleError) Error() string {
+	return fmt.Sprintf("Transfer blocked by the %s rule: %s", e.rule, e.message)
+}
+
+// checkTransferAges enforces the guild's membership and account age rules for both members
+func checkTransferAges(s *discordgo.Session, guildID, senderID, recipientID string, settings *utils.EconomySettings) error {
+	for _, userID := range []string{senderID, recipientID} {
+		who := fmt.Sprintf("<@%s>'s", userID)
+		if userID == senderID {
+			who = "Your"
+		}
+
+		if settings.TransferMinAccountDays > 0 {
+			created, err := discordgo.SnowflakeTimestamp(userID)
+			if err != nil {
+				return err
+			}
+			if time.Since(created) < time.Duration(settings.TransferMinAccountDays)*24*time.Hour {
+				return transferRuleError{"account age", fmt.Sprintf("%s Discord account must be at least %d days old.", who, settings.TransferMinAccountDays)}
+			}
+		}
+
+		if settings.TransferMinMemberDays > 0 {
+			member, err := s.GuildMember(guildID, userID)
+			if err != nil {
+				return err
+			}
+			if time.Since(member.JoinedAt) < time.Duration(settings.TransferMinMemberDays)*24*time.Hour {
+				return transferRuleError{"membership age", fmt.Sprintf("%s membership in this server must be at least %d days old.", who, settings.TransferMinMemberDays)}
+			}
+		}
+	}
+	return nil
+}
+
+// performTransfer checks the guild's rules, moves the coins and returns the message to show the sender.
+// A non-zero pendingID is the confirmation being used, consumed in the same transaction as the transfer
+// so it can only send once. The only error returned is errTransferClosed.
+func performTransfer(b *bot.Bot, s *discordgo.Session, guildID, senderID, recipientID string, amount, pendingID int64) (string, error) {
+	settings := GuildSettings(b, guildID)
+
+	err := checkTransferAges(s, guildID, senderID, recipientID, settings)
+	var ruleErr transferRuleError
+	if errors.As(err, &ruleErr) {
+		return ruleErr.Error(), nil
+	} else if err != nil {
+		log.Printf("Error checking transfer rules: %v", err)
+		return "An error occurred. Please try again.", nil
+	}
+
+	// Debit and credit happen in one transaction
+	var result *utils.TransferResult
+	err = b.Economy.WithTx(func(tx *sql.Tx) error {
+		if pendingID != 0 {
+			var status string
+			err := tx.QueryRow(`
+				SELECT status FROM pending_transfers WHERE transfer_id = $1 FOR UPDATE
+			`, pendingID).Scan(&status)
+			if err != nil {
+				return err
+			}
+			if status != "pending" {
+				return errTransferClosed
+			}
+			_, err = tx.Exec(`UPDATE pending_transfers SET status = 'sent' WHERE transfer_id = $1`, pendingID)
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		result, err = utils.TransferTx(tx, guildID, senderID, recipientID, amount, utils.ReasonTransfer, utils.TransferRules{
+			TaxPercent: settings.TransferTax,
+			DailyCap:   settings.TransferDailyCap,
+		})
+		return err
+	})
+	if errors.Is(err, errTransferClosed) {
+		return "", err
+	} else if errors.Is(err, utils.ErrInsufficientFunds) {
+		return fmt.Sprintf("Not enough %s to transfer.", settings.CurrencyName), nil
+	} else if errors.Is(err, utils.ErrTransferCapReached) {
+		remaining := settings.TransferDailyCap - result.SentToday
+		if remaining < 0 {
+			remaining = 0
+		}
+		return transferRuleError{"daily cap", fmt.Sprintf("You can send %s every 24 hours and have %s left.",
+			settings.Format(settings.TransferDailyCap), settings.Format(remaining))}.Error(), nil
+	} else if err != nil {
+		log.Printf("Error transferring coins: %v", err)
+		return "An error occurred. Please try again.", nil
+	}
+
+	received := amount - result.Tax
+	if channel, err := s.UserChannelCreate(recipientID); err == nil {
+		s.ChannelMessageSend(channel.ID, fmt.Sprintf("You received %s from <@%s> in server %s.", settings.Format(received), senderID, guildID))
+	}
+
+	message := fmt.Sprintf("You transferred %s to <@%s>.", settings.Format(received), recipientID)
+	if result.Tax > 0 {
+		message += fmt.Sprintf(" %s went to the server treasury as tax.", settings.Format(result.Tax))
+	}
+	return message, nil
 }
 
 func Transfer(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
@@ -34,6 +156,11 @@ func Transfer(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args
 		return
 	}
 
+	if recipientID == m.Author.ID {
+		s.ChannelMessageSend(m.ChannelID, "You cannot transfer coins to yourself.")
+		return
+	}
+
 	// Check if the recipient exists
 	exists, err := utils.UserExists(s, recipientID)
 	if err != nil {
@@ -47,7 +174,7 @@ func Transfer(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args
 	}
 
 	// Extract the amount
-	amount := 0
+	amount := int64(0)
 	fmt.Sscanf(args[2], "%d", &amount)
 
 	// Validate amount
@@ -56,34 +183,13 @@ func Transfer(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args
 		return
 	}
 
-	// Ensure sender exists in global users table
-	_, err = b.Db.Exec(`
-		INSERT INTO users (user_id, username, avatar, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		ON CONFLICT (user_id) DO UPDATE SET
-			username = EXCLUDED.username,
-			avatar = EXCLUDED.avatar,
-			updated_at = NOW()
-	`, m.Author.ID, m.Author.Username, m.Author.AvatarURL(""))
-	if err != nil {
-		log.Printf("Error upserting sender: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
-		return
-	}
-
-	// Ensure sender exists in guild_members table
-	_, err = b.Db.Exec(`
-		INSERT INTO guild_members (guild_id, user_id, joined_at)
-		VALUES ($1, $2, NOW())
-		ON CONFLICT (guild_id, user_id) DO NOTHING
-	`, m.GuildID, m.Author.ID)
-	if err != nil {
-		log.Printf("Error ensuring sender guild member: %v", err)
+	// Ensure sender and recipient exist in the users and guild_members tables
+	if err := b.Economy.EnsureMember(m.GuildID, m.Author); err != nil {
+		log.Printf("Error ensuring sender: %v", err)
 		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
 		return
 	}
 
-	// Ensure recipient exists in global users table
 	recipient, err := s.User(recipientID)
 	if err != nil {
 		log.Printf("Error getting recipient user: %v", err)
@@ -91,81 +197,131 @@ func Transfer(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args
 		return
 	}
 
-	_, err = b.Db.Exec(`
-		INSERT INTO users (user_id, username, avatar, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		ON CONFLICT (user_id) DO UPDATE SET
-			username = EXCLUDED.username,
-			avatar = EXCLUDED.avatar,
-			updated_at = NOW()
-	`, recipientID, recipient.Username, recipient.AvatarURL(""))
-	if err != nil {
-		log.Printf("Error upserting recipient: %v", err)
+	if err := b.Economy.EnsureMember(m.GuildID, recipient); err != nil {
+		log.Printf("Error ensuring recipient: %v", err)
 		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
 		return
 	}
 
-	// Ensure recipient exists in guild_members table
-	_, err = b.Db.Exec(`
-		INSERT INTO guild_members (guild_id, user_id, joined_at)
-		VALUES ($1, $2, NOW())
-		ON CONFLICT (guild_id, user_id) DO NOTHING
-	`, m.GuildID, recipientID)
-	if err != nil {
-		log.Printf("Error ensuring recipient guild member: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
-		return
-	}
+	settings := GuildSettings(b, m.GuildID)
 
-	// Get sender's balance
-	var senderBalance int
-	err = b.Db.QueryRow("SELECT balance FROM guild_members WHERE guild_id = $1 AND user_id = $2", m.GuildID, m.Author.ID).Scan(&senderBalance)
-	if err == sql.ErrNoRows {
-		s.ChannelMessageSend(m.ChannelID, "You have 0 coins.")
-		return
-	} else if err != nil {
-		log.Printf("Error querying sender balance: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+	// Large transfers wait for the sender to confirm
+	if settings.TransferConfirmAbove > 0 && amount >= settings.TransferConfirmAbove {
+		tax := int64(math.Floor(float64(amount) * settings.TransferTax / 100))
+
+		// The request is stored so each confirmation can only be used once
+		var pendingID int64
+		err := b.Db.QueryRow(`
+			INSERT INTO pending_transfers (guild_id, sender_id, recipient_id, amount)
+			VALUES ($1, $2, $3, $4)
+			RETURNING transfer_id
+		`, m.GuildID, m.Author.ID, recipientID, amount).Scan(&pendingID)
+		if err != nil {
+			log.Printf("Error storing transfer confirmation: %v", err)
+			s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+			return
+		}
+		id := strconv.FormatInt(pendingID, 10)
+
+		_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+			Content: fmt.Sprintf("Send %s to <@%s>? They will receive %s after tax.",
+				settings.Format(amount), recipientID, settings.Format(amount-tax)),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "Send",
+							Style:    discordgo.SuccessButton,
+							CustomID: commands.CustomID("transfer", "confirm", id),
+						},
+						discordgo.Button{
+							Label:    "Cancel",
+							Style:    discordgo.SecondaryButton,
+							CustomID: commands.CustomID("transfer", "cancel", id),
+						},
+					},
+				},
+			},
+		})
+		if err != nil {
+			log.Printf("Error sending transfer confirmation: %v", err)
+		}
 		return
 	}
 
-	// Check if sender has enough coins
-	if senderBalance < amount {
-		s.ChannelMessageSend(m.ChannelID, "Not enough coins to transfer.")
+	message, _ := performTransfer(b, s, m.GuildID, m.Author.ID, recipientID, amount, 0)
+	s.ChannelMessageSend(m.ChannelID, message)
+}
+
+// TransferButton handles the confirm and cancel buttons of a large transfer.
+// args are the action and the pending transfer ID.
+func TransferButton(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) < 2 {
 		return
 	}
-
-	// Get recipient's balance
-	var recipientBalance int
-	err = b.Db.QueryRow("SELECT balance FROM guild_members WHERE guild_id = $1 AND user_id = $2", m.GuildID, recipientID).Scan(&recipientBalance)
-	if err == sql.ErrNoRows {
-		// This shouldn't happen since we just inserted the row, but just in case
-		recipientBalance = 0
-	} else if err != nil {
-		log.Printf("Error querying recipient balance: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+	action := args[0]
+	pendingID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
 		return
 	}
 
-	// Deduct from sender's balance
-	_, err = b.Db.Exec("UPDATE guild_members SET balance = balance - $1 WHERE guild_id = $2 AND user_id = $3", amount, m.GuildID, m.Author.ID)
+	var senderID, recipientID, status string
+	var amount int64
+	var createdAt time.Time
+	err = b.Db.QueryRow(`
+		SELECT sender_id, recipient_id, amount, status, created_at
+		FROM pending_transfers
+		WHERE transfer_id = $1 AND guild_id = $2
+	`, pendingID, i.GuildID).Scan(&senderID, &recipientID, &amount, &status, &createdAt)
 	if err != nil {
-		log.Printf("Error updating sender balance: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		log.Printf("Error querying pending transfer %d: %v", pendingID, err)
+		utils.RespondEphemeral(s, i, "This transfer request could not be found.")
 		return
 	}
 
-	// Add to recipient's balance
-	_, err = b.Db.Exec("UPDATE guild_members SET balance = balance + $1 WHERE guild_id = $2 AND user_id = $3", amount, m.GuildID, recipientID)
-	if err != nil {
-		log.Printf("Error updating recipient balance: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+	if utils.InteractionUserID(i) != senderID {
+		utils.RespondEphemeral(s, i, "Only the sender can confirm this transfer.")
+		return
+	}
+	if status != "pending" {
+		utils.RespondEphemeral(s, i, "This transfer was already sent or cancelled.")
 		return
 	}
 
-	
+	var content string
+	switch {
+	case time.Since(createdAt) > transferConfirmTimeout:
+		content = "This transfer request has expired. Run `.transfer` again."
+	case action == "confirm":
+		content, err = performTransfer(b, s, i.GuildID, senderID, recipientID, amount, pendingID)
+		if errors.Is(err, errTransferClosed) {
+			utils.RespondEphemeral(s, i, "This transfer was already sent or cancelled.")
+			return
+		}
+	default:
+		result, err := b.Db.Exec(`
+			UPDATE pending_transfers SET status = 'cancelled' WHERE transfer_id = $1 AND status = 'pending'
+		`, pendingID)
+		if err != nil {
+			log.Printf("Error cancelling transfer %d: %v", pendingID, err)
+			utils.RespondEphemeral(s, i, "An error occurred. Please try again.")
+			return
+		}
+		if cancelled, _ := result.RowsAffected(); cancelled == 0 {
+			utils.RespondEphemeral(s, i, "This transfer was already sent or cancelled.")
+			return
+		}
+		content = "Transfer cancelled."
+	}
 
-	// Notify sender and recipient
-	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You transferred %d coins to <@%s>.", amount, recipientID))
-	s.ChannelMessageSend(recipientID, fmt.Sprintf("You received %d coins from <@%s> in server %s.", amount, m.Author.ID, m.GuildID))
-}
\ No newline at end of file
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+	if err != nil {
+		log.Printf("Error updating transfer message: %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package economy
+
+import (
+	"fmt"
+	"log"
+
+	"DiscordBot/bot"
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	economySubcommands["treasury"] = economyTreasury
+}
+
+// economyTreasury shows how much the guild has collected in transfer tax
+func economyTreasury(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	balance, err := b.Economy.TreasuryBalance(m.GuildID)
+	if err != nil {
+		log.Printf("Error querying treasury for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	settings := GuildSettings(b, m.GuildID)
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("🏛️ The server treasury holds %s from a %g%% transfer tax.", settings.Format(balance), settings.TransferTax))
+}
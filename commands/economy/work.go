@@ -2,6 +2,7 @@ package economy
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -11,6 +12,7 @@ import (
 
 	"DiscordBot/bot"
 	"DiscordBot/commands"
+	"DiscordBot/utils"
 	"github.com/bwmarrin/discordgo"
 	"github.com/lib/pq"
 )
@@ -19,16 +21,16 @@ func init() {
 	commands.RegisterCommand("work", Work)
 }
 
-// getUserDailyInfo retrieves user's last daily timestamp and base daily hours
-func getUserDailyInfo(b *bot.Bot, guildID, userID string) (sql.NullTime, int, error) {
+// getUserDailyInfo retrieves user's last daily timestamp and work streak
+func getUserDailyInfo(q utils.Querier, guildID, userID string) (sql.NullTime, int, error) {
 	var lastDaily sql.NullTime
-	var baseDailyHours int
-	err := b.Db.QueryRow(`
-		SELECT last_daily, base_daily_hours
+	var streak int
+	err := q.QueryRow(`
+		SELECT last_daily, COALESCE(work_streak, 0)
 		FROM guild_members
 		WHERE guild_id = $1 AND user_id = $2
-	`, guildID, userID).Scan(&lastDaily, &baseDailyHours)
-	return lastDaily, baseDailyHours, err
+	`, guildID, userID).Scan(&lastDaily, &streak)
+	return lastDaily, streak, err
 }
 
 // getRoleModifiers calculates the minimum hours and maximum multiplier based on user's roles
@@ -80,18 +82,22 @@ func getRoleModifiers(b *bot.Bot, guildID, userID string, s *discordgo.Session)
 	return minHours, maxMultiplier, rows.Err()
 }
 
+// workCooldown is the guild cooldown, shortened by the member's best role modifier
+func workCooldown(baseDailyHours, minHours int) time.Duration {
+	waitHours := baseDailyHours
+	if minHours > 0 && minHours < baseDailyHours {
+		waitHours = minHours
+	}
+	return time.Duration(waitHours) * time.Hour
+}
+
 // calculateWaitTime calculates the wait time based on last daily and role modifiers
 func calculateWaitTime(lastDaily sql.NullTime, baseDailyHours, minHours int) time.Duration {
 	if !lastDaily.Valid {
 		return 0
 	}
 
-	waitHours := baseDailyHours
-	if minHours > 0 && minHours < baseDailyHours {
-		waitHours = minHours
-	}
-
-	waitDuration := time.Duration(waitHours) * time.Hour
+	waitDuration := workCooldown(baseDailyHours, minHours)
 	elapsed := time.Since(lastDaily.Time)
 
 	if elapsed >= waitDuration {
@@ -101,43 +107,67 @@ func calculateWaitTime(lastDaily sql.NullTime, baseDailyHours, minHours int) tim
 	return waitDuration - elapsed
 }
 
-func Work(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
-	if m.GuildID == "" {
-		return
+// activeStreak returns the stored streak, or 0 once the cooldown and grace period have both passed
+func activeStreak(lastDaily sql.NullTime, streak int, cooldown time.Duration, settings *utils.EconomySettings) int {
+	grace := time.Duration(settings.StreakGraceHours) * time.Hour
+	if !lastDaily.Valid || time.Since(lastDaily.Time) > cooldown+grace {
+		return 0
 	}
+	return streak
+}
 
-	_, err := b.Db.Exec(`
-		INSERT INTO users (user_id, username, avatar)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (user_id) DO UPDATE SET
-			username = EXCLUDED.username,
-			avatar = EXCLUDED.avatar,
-			updated_at = NOW()
-	`, m.Author.ID, m.Author.Username, m.Author.AvatarURL(""))
+// streakMultiplier turns a streak into a payout multiplier. The first work of a
+// streak gets no bonus.
+func streakMultiplier(streak int, settings *utils.EconomySettings) float64 {
+	if streak <= 1 {
+		return 1.0
+	}
+	bonus := math.Min(float64(streak-1)*settings.StreakBonus, settings.StreakMaxBonus)
+	return 1 + bonus/100
+}
+
+// memberStreak returns a member's current work streak
+func memberStreak(b *bot.Bot, s *discordgo.Session, guildID, userID string, settings *utils.EconomySettings) (int, error) {
+	lastDaily, streak, err := getUserDailyInfo(b.Db, guildID, userID)
 	if err != nil {
-		log.Printf("Error upserting user: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
-		return
+		return 0, err
 	}
 
-	_, err = b.Db.Exec(`
-		INSERT INTO guild_members (guild_id, user_id)
-		VALUES ($1, $2)
-		ON CONFLICT (guild_id, user_id) DO NOTHING
-	`, m.GuildID, m.Author.ID)
+	memberJob, _, _, err := getMemberJob(b.Db, guildID, userID)
 	if err != nil {
-		log.Printf("Error ensuring guild member: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return 0, err
+	}
+
+	minHours, _, err := getRoleModifiers(b, guildID, userID, s)
+	if err != nil {
+		log.Printf("Error getting role modifiers: %v", err)
+		minHours = 0
+	}
+	return activeStreak(lastDaily, streak, workCooldown(memberJob.cooldownHours(settings), minHours), settings), nil
+}
+
+// errWorkCooldown aborts the work transaction while the member is still on cooldown
+type errWorkCooldown struct {
+	wait time.Duration
+}
+
+func (e errWorkCooldown) Error() string {
+	return fmt.Sprintf("work is on cooldown for %s", e.wait)
+}
+
+func Work(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	if m.GuildID == "" {
 		return
 	}
 
-	lastDaily, baseDailyHours, err := getUserDailyInfo(b, m.GuildID, m.Author.ID)
-	if err != nil && err != sql.ErrNoRows {
-		log.Printf("Error getting user daily info: %v", err)
+	if err := b.Economy.EnsureMember(m.GuildID, m.Author); err != nil {
+		log.Printf("Error ensuring member: %v", err)
 		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
 		return
 	}
 
+	settings := GuildSettings(b, m.GuildID)
+
 	minHours, maxMultiplier, err := getRoleModifiers(b, m.GuildID, m.Author.ID, s)
 	if err != nil {
 		log.Printf("Error getting role modifiers: %v", err)
@@ -145,28 +175,87 @@ func Work(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []s
 		maxMultiplier = 1.0
 	}
 
-	waitTime := calculateWaitTime(lastDaily, baseDailyHours, minHours)
+	// The cooldown check and the payout share one transaction with the member
+	// row locked, so repeated .work calls can't pay out twice
+	var reward int64
+	var streak, jobShifts int
+	var memberJob *job
+	err = b.Economy.WithTx(func(tx *sql.Tx) error {
+		if _, err := utils.LockBalance(tx, m.GuildID, m.Author.ID); err != nil {
+			return err
+		}
+
+		lastDaily, lastStreak, err := getUserDailyInfo(tx, m.GuildID, m.Author.ID)
+		if err != nil {
+			return err
+		}
+
+		// A job replaces the guild's pay range and cooldown, role modifiers still apply on top
+		memberJob, jobShifts, _, err = getMemberJob(tx, m.GuildID, m.Author.ID)
+		if err != nil {
+			return err
+		}
+		cooldownHours := memberJob.cooldownHours(settings)
+		minPayout, maxPayout := settings.WorkMinPayout, settings.WorkMaxPayout
+		levelMultiplier := 1.0
+		if memberJob != nil {
+			minPayout, maxPayout = memberJob.MinPayout, memberJob.MaxPayout
+			levelMultiplier = jobLevelMultiplier(jobLevel(jobShifts))
+		}
 
-	if waitTime <= 0 {
-		baseReward := rand.Intn(650-65) + 65
-		reward := int(math.Round(float64(baseReward) * maxMultiplier))
+		if waitTime := calculateWaitTime(lastDaily, cooldownHours, minHours); waitTime > 0 {
+			return errWorkCooldown{wait: waitTime}
+		}
 
-		_, err := b.Db.Exec(`
-			UPDATE guild_members 
-			SET balance = balance + $1, last_daily = NOW() 
-			WHERE guild_id = $2 AND user_id = $3
-		`, reward, m.GuildID, m.Author.ID)
+		// Working again before the grace period runs out keeps the streak going
+		streak = activeStreak(lastDaily, lastStreak, workCooldown(cooldownHours, minHours), settings) + 1
+
+		// The job level and streak bonuses stack on top of the role multiplier
+		baseReward := minPayout + rand.Int63n(maxPayout-minPayout+1)
+		reward = int64(math.Round(float64(baseReward) * maxMultiplier * levelMultiplier * streakMultiplier(streak, settings)))
+
+		_, err = utils.ApplyChange(tx, utils.BalanceChange{
+			GuildID: m.GuildID,
+			UserID:  m.Author.ID,
+			Amount:  reward,
+			Reason:  utils.ReasonWork,
+			ActorID: m.Author.ID,
+		})
 		if err != nil {
-			log.Printf("Error updating user balance: %v", err)
-			s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
-			return
+			return err
 		}
 
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You received %d coins!", reward))
-	} else {
-		hours := int(waitTime.Hours())
-		minutes := int(waitTime.Minutes()) % 60
+		_, err = tx.Exec(`
+			UPDATE guild_members
+			SET last_daily = NOW(), work_streak = $3,
+				job_shifts = job_shifts + CASE WHEN job_id IS NULL THEN 0 ELSE 1 END,
+				total_shifts = total_shifts + 1
+			WHERE guild_id = $1 AND user_id = $2
+		`, m.GuildID, m.Author.ID, streak)
+		return err
+	})
+
+	var cooldown errWorkCooldown
+	if errors.As(err, &cooldown) {
+		hours := int(cooldown.wait.Hours())
+		minutes := int(cooldown.wait.Minutes()) % 60
 		formattedWaitTime := fmt.Sprintf("%d hours %d minutes", hours, minutes)
 		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You have to wait %s before you can work again", formattedWaitTime))
+		return
+	} else if err != nil {
+		log.Printf("Error paying work reward: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	message := fmt.Sprintf("You received %s! 🔥 Streak: %d", settings.Format(reward), streak)
+	if multiplier := streakMultiplier(streak, settings); multiplier > 1 {
+		message += fmt.Sprintf(" (+%g%% bonus)", math.Round((multiplier-1)*10000)/100)
+	}
+	if memberJob != nil {
+		if level := jobLevel(jobShifts + 1); level > jobLevel(jobShifts) {
+			message += fmt.Sprintf("\n🎉 You reached level %d as %s! Your pay is now +%d%%.", level, memberJob.Name, (level-1)*jobLevelBonus)
+		}
 	}
+	s.ChannelMessageSend(m.ChannelID, message)
 }
@@ -21,70 +21,12 @@ func Help(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []s
 	}
 
 	if len(args) > 1 {
-		commandName := strings.ToLower(args[1])
-		
-		// Resolve alias to actual command name
-		if actualName, isAlias := CommandAliases[commandName]; isAlias {
-			commandName = actualName
-		}
-		
-		// Check if the command exists
-		commandInfo, exists := CommandDetails[commandName]
-		if !exists {
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Command `%s` not found.", commandName))
-			return
-		}
-		
-		// Check if the command is disabled
-		var count int
-		err := b.Db.QueryRow("SELECT COUNT(*) FROM disabled_commands WHERE guild_id = $1 AND name = $2 AND type = 'command'",
-			m.GuildID, commandName).Scan(&count)
-		if err != nil && err != sql.ErrNoRows {
-			log.Printf("Error checking if command %s is disabled in guild %s: %v", commandName, m.GuildID, err)
-		} else if count > 0 {
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Command `%s` is disabled.", commandName))
+		embed, err := CommandHelpEmbed(b, m.GuildID, args[1])
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, err.Error())
 			return
 		}
 
-		// Check if the command's category is disabled
-		category := commandInfo.Category
-		err = b.Db.QueryRow("SELECT COUNT(*) FROM disabled_commands WHERE guild_id = $1 AND name = $2 AND type = 'category'",
-			m.GuildID, strings.ToLower(category)).Scan(&count)
-		if err != nil && err != sql.ErrNoRows {
-			log.Printf("Error checking if category %s is disabled in guild %s: %v", category, m.GuildID, err)
-		} else if count > 0 {
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Command `%s` is in category `%s` which is disabled.", commandName, category))
-			return
-		}
-		
-		// Build help embed for the specific command
-		embed := &discordgo.MessageEmbed{
-			Title:       fmt.Sprintf("Help: %s", commandInfo.Name),
-			Description: commandInfo.Description,
-			Color:       0x00ff00,
-			Fields: []*discordgo.MessageEmbedField{
-				{
-					Name:  "Usage",
-					Value: fmt.Sprintf("`%s`", commandInfo.Usage),
-				},
-			},
-		}
-		
-		// Add aliases if they exist
-		if len(commandInfo.Aliases) > 0 {
-			aliases := strings.Join(commandInfo.Aliases, ", ")
-			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-				Name:  "Aliases",
-				Value: aliases,
-			})
-		}
-		
-		// Add category
-		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-			Name:  "Category",
-			Value: commandInfo.Category,
-		})
-		
 		s.ChannelMessageSendEmbed(m.ChannelID, embed)
 		return
 	}
@@ -106,4 +48,71 @@ func Help(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []s
 	}
 
 	s.ChannelMessageSendEmbed(m.ChannelID, embed)
-}
\ No newline at end of file
+}
+
+// CommandHelpEmbed builds the help embed for a single command or alias.
+// Returned errors are safe to show to users.
+func CommandHelpEmbed(b *bot.Bot, guildID, commandName string) (*discordgo.MessageEmbed, error) {
+	commandName = strings.ToLower(commandName)
+
+	// Resolve alias to actual command name
+	if actualName, isAlias := CommandAliases[commandName]; isAlias {
+		commandName = actualName
+	}
+
+	// Check if the command exists
+	commandInfo, exists := CommandDetails[commandName]
+	if !exists {
+		return nil, fmt.Errorf("Command `%s` not found.", commandName)
+	}
+
+	// Check if the command is disabled
+	var count int
+	err := b.Db.QueryRow("SELECT COUNT(*) FROM disabled_commands WHERE guild_id = $1 AND name = $2 AND type = 'command'",
+		guildID, commandName).Scan(&count)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error checking if command %s is disabled in guild %s: %v", commandName, guildID, err)
+	} else if count > 0 {
+		return nil, fmt.Errorf("Command `%s` is disabled.", commandName)
+	}
+
+	// Check if the command's category is disabled
+	category := commandInfo.Category
+	err = b.Db.QueryRow("SELECT COUNT(*) FROM disabled_commands WHERE guild_id = $1 AND name = $2 AND type = 'category'",
+		guildID, strings.ToLower(category)).Scan(&count)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error checking if category %s is disabled in guild %s: %v", category, guildID, err)
+	} else if count > 0 {
+		return nil, fmt.Errorf("Command `%s` is in category `%s` which is disabled.", commandName, category)
+	}
+
+	// Build help embed for the specific command
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Help: %s", commandInfo.Name),
+		Description: commandInfo.Description,
+		Color:       0x00ff00,
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:  "Usage",
+				Value: fmt.Sprintf("`%s`", commandInfo.Usage),
+			},
+		},
+	}
+
+	// Add aliases if they exist
+	if len(commandInfo.Aliases) > 0 {
+		aliases := strings.Join(commandInfo.Aliases, ", ")
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Aliases",
+			Value: aliases,
+		})
+	}
+
+	// Add category
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:  "Category",
+		Value: commandInfo.Category,
+	})
+
+	return embed, nil
+}
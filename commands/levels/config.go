@@ -0,0 +1,324 @@
+package levels
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	maxXPPerMessage  = 1000
+	maxXPMultiplier  = 10
+	maxRewardedLevel = 1000
+)
+
+// configureLevels handles the admin subcommands of .level
+func configureLevels(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, config *utils.XPConfig, args []string) {
+	// Check if the user has administrator permissions
+	hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking admin status: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if !hasAdmin {
+		s.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command.")
+		return
+	}
+
+	var reply string
+	switch strings.ToLower(args[0]) {
+	case "config":
+		showLevelConfig(b, s, m, config)
+		return
+
+	case "enable", "disable":
+		enabled := strings.ToLower(args[0]) == "enable"
+		err = execLevelUpdate(b, `UPDATE guilds SET xp_enabled = $1 WHERE guild_id = $2`, enabled, m.GuildID)
+		reply = "Chat XP disabled. Members keep the XP they have."
+		if enabled {
+			reply = "Chat XP enabled. Members earn XP for chatting, check it with `.level`."
+		}
+
+	case "rate":
+		var min, max int64 = -1, -1
+		if len(args) >= 3 {
+			min, _ = strconv.ParseInt(args[1], 10, 64)
+			max, _ = strconv.ParseInt(args[2], 10, 64)
+		}
+		if min < 1 || max < min || max > maxXPPerMessage {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Usage: .level rate <min> <max>, between 1 and %d XP per message", maxXPPerMessage))
+			return
+		}
+		err = execLevelUpdate(b, `UPDATE guilds SET xp_min = $1, xp_max = $2 WHERE guild_id = $3`, min, max, m.GuildID)
+		reply = fmt.Sprintf("Messages now earn %d - %d XP.", min, max)
+
+	case "cooldown":
+		seconds := -1
+		if len(args) >= 2 {
+			if n, err := strconv.Atoi(strings.TrimSuffix(args[1], "s")); err == nil {
+				seconds = n
+			}
+		}
+		if seconds < 0 || seconds > 86400 {
+			s.ChannelMessageSend(m.ChannelID, "Usage: .level cooldown <seconds>, between 0 and 86400")
+			return
+		}
+		err = execLevelUpdate(b, `UPDATE guilds SET xp_cooldown_seconds = $1 WHERE guild_id = $2`, seconds, m.GuildID)
+		reply = fmt.Sprintf("Members earn XP at most once every %s.", time.Duration(seconds)*time.Second)
+
+	case "curve":
+		var base, exponent float64
+		if len(args) >= 3 {
+			base, _ = strconv.ParseFloat(args[1], 64)
+			exponent, _ = strconv.ParseFloat(args[2], 64)
+		}
+		// ParseFloat accepts NaN, which fails every comparison
+		if math.IsNaN(base) || math.IsNaN(exponent) || base < 1 || base > 100000 || exponent < 1 || exponent > 3 {
+			s.ChannelMessageSend(m.ChannelID, "Usage: .level curve <base> <exponent>. Level n needs base × n^exponent XP, with a base of 1 to 100000 and an exponent of 1 to 3.")
+			return
+		}
+		err = b.Economy.WithTx(func(tx *sql.Tx) error {
+			_, err := tx.Exec(`UPDATE guilds SET xp_curve_base = $1, xp_curve_exponent = $2 WHERE guild_id = $3`, base, exponent, m.GuildID)
+			if err != nil {
+				return err
+			}
+			// Stored levels follow the new curve so nobody is announced for levels they already had
+			_, err = tx.Exec(`
+				UPDATE member_levels SET level = FLOOR(POWER(xp / $1::float8, 1 / $2::float8))
+				WHERE guild_id = $3
+			`, base, exponent, m.GuildID)
+			return err
+		})
+		config.CurveBase, config.CurveExponent = base, exponent
+		reply = fmt.Sprintf("Levels now follow %g × level^%g XP. Level 10 takes %d XP and level 50 takes %d XP.",
+			base, exponent, config.XPForLevel(10), config.XPForLevel(50))
+
+	case "announce":
+		if len(args) < 2 {
+			s.ChannelMessageSend(m.ChannelID, "Usage: .level announce channel|dm|off|<#channel>")
+			return
+		}
+		switch mode := strings.ToLower(args[1]); mode {
+		case utils.XPAnnounceChannel, utils.XPAnnounceDM, utils.XPAnnounceOff:
+			err = execLevelUpdate(b, `UPDATE guilds SET xp_announce = $1, xp_announce_channel_id = NULL WHERE guild_id = $2`, mode, m.GuildID)
+			reply = map[string]string{
+				utils.XPAnnounceChannel: "Level-ups are announced in the channel the member is chatting in.",
+				utils.XPAnnounceDM:      "Level-ups are sent to members by DM.",
+				utils.XPAnnounceOff:     "Level-ups are no longer announced.",
+			}[mode]
+		default:
+			channelID, ok := guildChannel(s, m.GuildID, args[1])
+			if !ok {
+				s.ChannelMessageSend(m.ChannelID, "Invalid channel. Please mention a channel in this server.")
+				return
+			}
+			err = execLevelUpdate(b, `UPDATE guilds SET xp_announce = $1, xp_announce_channel_id = $2 WHERE guild_id = $3`, utils.XPAnnounceChannel, channelID, m.GuildID)
+			reply = fmt.Sprintf("Level-ups are announced in <#%s>.", channelID)
+		}
+
+	case "exclude", "include":
+		if len(args) < 2 {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Usage: .level %s <#channel>", strings.ToLower(args[0])))
+			return
+		}
+		channelID, ok := guildChannel(s, m.GuildID, args[1])
+		if !ok {
+			s.ChannelMessageSend(m.ChannelID, "Invalid channel. Please mention a channel in this server.")
+			return
+		}
+		if strings.ToLower(args[0]) == "exclude" {
+			err = execLevelUpdate(b, `
+				UPDATE guilds
+				SET xp_excluded_channels = array_append(array_remove(COALESCE(xp_excluded_channels, '{}'), $1), $1)
+				WHERE guild_id = $2
+			`, channelID, m.GuildID)
+			reply = fmt.Sprintf("Messages in <#%s> no longer earn XP.", channelID)
+		} else {
+			err = execLevelUpdate(b, `
+				UPDATE guilds
+				SET xp_excluded_channels = array_remove(COALESCE(xp_excluded_channels, '{}'), $1)
+				WHERE guild_id = $2
+			`, channelID, m.GuildID)
+			reply = fmt.Sprintf("Messages in <#%s> earn XP again.", channelID)
+		}
+
+	case "multiplier":
+		if len(args) < 3 {
+			s.ChannelMessageSend(m.ChannelID, "Usage: .level multiplier <role> <x|none>, e.g. `.level multiplier @Booster 1.5`")
+			return
+		}
+		value := strings.ToLower(strings.TrimSuffix(args[len(args)-1], "x"))
+		roleInput := strings.Join(args[1:len(args)-1], " ")
+		role, ferr := utils.FindRole(s, m.GuildID, roleInput)
+		if ferr != nil {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Role '%s' not found.", roleInput))
+			return
+		}
+		if value == "none" {
+			err = execLevelUpdate(b, `DELETE FROM role_xp_multipliers WHERE guild_id = $1 AND role_id = $2`, m.GuildID, role.ID)
+			reply = fmt.Sprintf("<@&%s> no longer changes the XP its members earn.", role.ID)
+			break
+		}
+		multiplier, perr := strconv.ParseFloat(value, 64)
+		if perr != nil || math.IsNaN(multiplier) || multiplier < 0 || multiplier > maxXPMultiplier {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("The multiplier must be a number between 0 and %d.", maxXPMultiplier))
+			return
+		}
+		err = execLevelUpdate(b, `
+			INSERT INTO role_xp_multipliers (guild_id, role_id, multiplier)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (guild_id, role_id) DO UPDATE SET multiplier = EXCLUDED.multiplier
+		`, m.GuildID, role.ID, multiplier)
+		reply = fmt.Sprintf("Members with <@&%s> now earn %gx XP. A member with several multiplier roles gets the best one.", role.ID, multiplier)
+
+	case "role":
+		if len(args) < 3 {
+			s.ChannelMessageSend(m.ChannelID, "Usage: .level role <level> <role|none>")
+			return
+		}
+		level, perr := strconv.Atoi(args[1])
+		if perr != nil || level < 1 || level > maxRewardedLevel {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("The level must be a number between 1 and %d.", maxRewardedLevel))
+			return
+		}
+		roleInput := strings.Join(args[2:], " ")
+		if strings.EqualFold(roleInput, "none") {
+			err = execLevelUpdate(b, `DELETE FROM level_roles WHERE guild_id = $1 AND level = $2`, m.GuildID, level)
+			reply = fmt.Sprintf("Level %d no longer awards a role.", level)
+			break
+		}
+		role, ferr := utils.FindRole(s, m.GuildID, roleInput)
+		if ferr != nil {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Role '%s' not found.", roleInput))
+			return
+		}
+		err = execLevelUpdate(b, `
+			INSERT INTO level_roles (guild_id, level, role_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (guild_id, level) DO UPDATE SET role_id = EXCLUDED.role_id
+		`, m.GuildID, level, role.ID)
+		reply = fmt.Sprintf("Members reaching level %d now receive <@&%s>.", level, role.ID)
+
+	case "roles":
+		mode := ""
+		if len(args) >= 2 {
+			mode = strings.ToLower(args[1])
+		}
+		if mode != "stack" && mode != "replace" {
+			s.ChannelMessageSend(m.ChannelID, "Usage: .level roles stack|replace")
+			return
+		}
+		err = execLevelUpdate(b, `UPDATE guilds SET xp_stack_roles = $1 WHERE guild_id = $2`, mode == "stack", m.GuildID)
+		reply = "Members keep every level role they reach."
+		if mode == "replace" {
+			reply = "Members only keep the level role of the highest level they reached."
+		}
+	}
+
+	if err != nil {
+		log.Printf("Error configuring levels for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "Error updating the level settings. Please try again later.")
+		return
+	}
+	s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content:         reply,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+}
+
+func execLevelUpdate(b *bot.Bot, query string, args ...interface{}) error {
+	_, err := b.Db.Exec(query, args...)
+	return err
+}
+
+// guildChannel resolves a channel mention or ID to a channel of the guild
+func guildChannel(s *discordgo.Session, guildID, input string) (string, bool) {
+	channelID := strings.TrimSuffix(strings.TrimPrefix(input, "<#"), ">")
+	channel, err := s.Channel(channelID)
+	if err != nil || channel.GuildID != guildID {
+		return "", false
+	}
+	return channelID, true
+}
+
+// showLevelConfig shows the guild's XP settings, multipliers and level roles
+func showLevelConfig(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, config *utils.XPConfig) {
+	status := "Disabled"
+	if config.Enabled {
+		status = "Enabled"
+	}
+	announce := config.Announce
+	if config.Announce == utils.XPAnnounceChannel && config.AnnounceChannelID != "" {
+		announce = fmt.Sprintf("<#%s>", config.AnnounceChannelID)
+	}
+	excluded := "None"
+	if len(config.ExcludedChannels) > 0 {
+		excluded = "<#" + strings.Join(config.ExcludedChannels, "> <#") + ">"
+	}
+	roleMode := "Stack"
+	if !config.StackRoles {
+		roleMode = "Replace"
+	}
+
+	multipliers, err := levelConfigLines(b, `
+		SELECT format('<@&%s>: %sx', role_id, multiplier) FROM role_xp_multipliers WHERE guild_id = $1 ORDER BY multiplier DESC
+	`, m.GuildID)
+	if err != nil {
+		log.Printf("Error querying XP multipliers: %v", err)
+	}
+	roles, err := levelConfigLines(b, `
+		SELECT format('Level %s: <@&%s>', level, role_id) FROM level_roles WHERE guild_id = $1 ORDER BY level
+	`, m.GuildID)
+	if err != nil {
+		log.Printf("Error querying level roles: %v", err)
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+		Title: "Level Settings",
+		Color: 0x5865f2,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Status", Value: status, Inline: true},
+			{Name: "XP per Message", Value: fmt.Sprintf("%d - %d", config.MinXP, config.MaxXP), Inline: true},
+			{Name: "Cooldown", Value: config.Cooldown.String(), Inline: true},
+			{Name: "Curve", Value: fmt.Sprintf("%g × level^%g", config.CurveBase, config.CurveExponent), Inline: true},
+			{Name: "Announcements", Value: announce, Inline: true},
+			{Name: "Level Roles", Value: roleMode, Inline: true},
+			{Name: "Excluded Channels", Value: excluded},
+			{Name: "Role Multipliers", Value: multipliers},
+			{Name: "Role Rewards", Value: roles},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: "Change a setting with .level <setting> <value>"},
+	})
+}
+
+// levelConfigLines runs a query returning one line per row, or "None"
+func levelConfigLines(b *bot.Bot, query, guildID string) (string, error) {
+	rows, err := b.Db.Query(query, guildID)
+	if err != nil {
+		return "None", err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "None", err
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "None", rows.Err()
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
@@ -0,0 +1,165 @@
+package levels
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	levelsPerPage  = 10
+	progressBarLen = 20
+)
+
+const levelUsage = "Usage:\n" +
+	"`.level [@user]` - show a level and XP\n" +
+	"`.level top [page]` - show the XP leaderboard\n" +
+	"Admins: `.level config`, `.level enable|disable`, `.level rate <min> <max>`, `.level cooldown <seconds>`, " +
+	"`.level curve <base> <exponent>`, `.level announce channel|dm|off|<#channel>`, `.level exclude|include <#channel>`, " +
+	"`.level multiplier <role> <x|none>`, `.level role <level> <role|none>`, `.level roles stack|replace`"
+
+func init() {
+	commands.RegisterCommand("level", Level, "lvl", "xp")
+}
+
+// Level shows a member's chat level and XP, or the guild's XP leaderboard, and lets
+// admins configure chat XP
+func Level(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	config, err := utils.LoadXPConfig(b.Db, m.GuildID)
+	if err != nil {
+		log.Printf("Error loading XP config for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if len(args) >= 2 {
+		switch strings.ToLower(args[1]) {
+		case "config", "enable", "disable", "rate", "cooldown", "curve", "announce", "exclude", "include", "multiplier", "role", "roles":
+			configureLevels(b, s, m, config, args[1:])
+			return
+		}
+	}
+
+	if len(args) >= 2 && strings.ToLower(args[1]) == "top" {
+		page := 1
+		if len(args) >= 3 {
+			page, err = strconv.Atoi(args[2])
+			if err != nil || page < 1 {
+				s.ChannelMessageSend(m.ChannelID, "Usage: .level top [page]")
+				return
+			}
+		}
+		levelLeaderboard(b, s, m, config, page)
+		return
+	}
+
+	target := m.Author
+	if len(args) >= 2 {
+		targetUserID, err := utils.ExtractUserID(args[1])
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, levelUsage)
+			return
+		}
+
+		member, err := s.GuildMember(m.GuildID, targetUserID)
+		if err != nil || member == nil {
+			s.ChannelMessageSend(m.ChannelID, "mentioned user is not in this server.")
+			return
+		}
+		target = member.User
+	}
+
+	standing, err := utils.GetMemberLevel(b.Db, m.GuildID, target.ID)
+	if err != nil {
+		log.Printf("Error querying level: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	reps, _, err := memberRep(b, m.GuildID, target.ID)
+	if err != nil {
+		log.Printf("Error querying rep: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	level := config.LevelForXP(standing.XP)
+	current, next := config.XPForLevel(level), config.XPForLevel(level+1)
+	progress := float64(standing.XP-current) / float64(next-current)
+	filled := int(progress * progressBarLen)
+
+	embed := &discordgo.MessageEmbed{
+		Title:     fmt.Sprintf("%s's Level", target.Username),
+		Color:     0x5865f2,
+		Thumbnail: &discordgo.MessageEmbedThumbnail{URL: target.AvatarURL("")},
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Level", Value: strconv.Itoa(level), Inline: true},
+			{Name: "XP", Value: strconv.FormatInt(standing.XP, 10), Inline: true},
+			{Name: "Rank", Value: fmt.Sprintf("#%d", standing.Rank), Inline: true},
+			{Name: "Reputation", Value: fmt.Sprintf("⭐ %d", reps), Inline: true},
+			{
+				Name: fmt.Sprintf("Progress to Level %d", level+1),
+				Value: fmt.Sprintf("%s%s %d / %d XP", strings.Repeat("█", filled), strings.Repeat("░", progressBarLen-filled),
+					standing.XP-current, next-current),
+			},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("%d messages counted", standing.Messages)},
+	}
+	if !config.Enabled {
+		embed.Footer.Text += " • Chat XP is disabled in this server"
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, embed)
+}
+
+func levelLeaderboard(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, config *utils.XPConfig, page int) {
+	rows, err := b.Db.Query(`
+		SELECT user_id, xp
+		FROM member_levels
+		WHERE guild_id = $1 AND xp > 0
+		ORDER BY xp DESC, user_id
+		LIMIT $2 OFFSET $3
+	`, m.GuildID, levelsPerPage, (page-1)*levelsPerPage)
+	if err != nil {
+		log.Printf("Error querying XP leaderboard: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving the leaderboard.")
+		return
+	}
+	defer rows.Close()
+
+	var lines []string
+	position := (page - 1) * levelsPerPage
+	for rows.Next() {
+		var userID string
+		var xp int64
+		if err := rows.Scan(&userID, &xp); err != nil {
+			log.Printf("Error scanning XP leaderboard: %v", err)
+			continue
+		}
+		position++
+		lines = append(lines, fmt.Sprintf("%d. <@%s> - Level %d (%d XP)", position, userID, config.LevelForXP(xp), xp))
+	}
+
+	if len(lines) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "No one has earned XP on that page yet.")
+		return
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+		Title:       "XP Leaderboard",
+		Description: strings.Join(lines, "\n"),
+		Color:       0x5865f2,
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Page %d • .level top <page>", page)},
+	})
+}
@@ -0,0 +1,422 @@
+package levels
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	repCooldown     = 24 * time.Hour
+	maxRepReason    = 200
+	maxRepMilestone = 100000
+	recentRepsShown = 5
+)
+
+const repUsage = "Usage:\n" +
+	"`.rep @user [reason]` - give a reputation point, once a day\n" +
+	"`.rep show [@user]` - show reputation and the latest reps\n" +
+	"`.rep top [page]` - show the reputation leaderboard\n" +
+	"`.rep milestones` - list the roles awarded for reputation\n" +
+	"Admins: `.rep milestone <reps> <role|none>`"
+
+// errRepSameMember stops a member from repping the same member twice in a row
+var errRepSameMember = errors.New("already gave the last rep to this member")
+
+// errRepCooldown aborts the rep transaction while the giver is still on cooldown
+type errRepCooldown struct {
+	wait time.Duration
+}
+
+func (e errRepCooldown) Error() string {
+	return fmt.Sprintf("rep is on cooldown for %s", e.wait)
+}
+
+func init() {
+	commands.RegisterCommand("rep", Rep, "reputation")
+}
+
+// Rep gives reputation points and shows reputation, its leaderboard and milestone roles
+func Rep(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	if len(args) < 2 {
+		showRep(b, s, m, m.Author)
+		return
+	}
+
+	switch strings.ToLower(args[1]) {
+	case "show":
+		target := m.Author
+		if len(args) >= 3 {
+			member, ok := repMember(s, m, args[2])
+			if !ok {
+				return
+			}
+			target = member.User
+		}
+		showRep(b, s, m, target)
+		return
+
+	case "top":
+		page := 1
+		if len(args) >= 3 {
+			var err error
+			page, err = strconv.Atoi(args[2])
+			if err != nil || page < 1 {
+				s.ChannelMessageSend(m.ChannelID, "Usage: .rep top [page]")
+				return
+			}
+		}
+		repLeaderboard(b, s, m, page)
+		return
+
+	case "milestones":
+		lines, err := levelConfigLines(b, `
+			SELECT format('%s rep: <@&%s>', reps, role_id) FROM rep_roles WHERE guild_id = $1 ORDER BY reps
+		`, m.GuildID)
+		if err != nil {
+			log.Printf("Error querying rep milestones: %v", err)
+			s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+			return
+		}
+		s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+			Title:       "Reputation Milestones",
+			Description: lines,
+			Color:       0x5865f2,
+		})
+		return
+
+	case "milestone":
+		setRepMilestone(b, s, m, args[2:])
+		return
+	}
+
+	giveRep(b, s, m, args)
+}
+
+// repMember resolves a mention to a member of the guild, replying if it can't
+func repMember(s *discordgo.Session, m *discordgo.MessageCreate, mention string) (*discordgo.Member, bool) {
+	targetUserID, err := utils.ExtractUserID(mention)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, repUsage)
+		return nil, false
+	}
+
+	member, err := s.GuildMember(m.GuildID, targetUserID)
+	if err != nil || member == nil {
+		s.ChannelMessageSend(m.ChannelID, "mentioned user is not in this server.")
+		return nil, false
+	}
+	return member, true
+}
+
+func giveRep(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	member, ok := repMember(s, m, args[1])
+	if !ok {
+		return
+	}
+	if member.User.ID == m.Author.ID {
+		s.ChannelMessageSend(m.ChannelID, "You can't give reputation to yourself.")
+		return
+	}
+	if member.User.Bot {
+		s.ChannelMessageSend(m.ChannelID, "You can't give reputation to bots.")
+		return
+	}
+
+	reason := strings.TrimSpace(strings.Join(args[2:], " "))
+	if len([]rune(reason)) > maxRepReason {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("The reason can be at most %d characters.", maxRepReason))
+		return
+	}
+
+	// The giver's row is locked while the limits are checked, so repeated .rep calls
+	// can't give more than one rep a day
+	var total int
+	err := b.Economy.WithTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO rep_givers (guild_id, user_id) VALUES ($1, $2)
+			ON CONFLICT (guild_id, user_id) DO NOTHING
+		`, m.GuildID, m.Author.ID)
+		if err != nil {
+			return err
+		}
+
+		var lastGivenAt sql.NullTime
+		var lastReceiverID sql.NullString
+		err = tx.QueryRow(`
+			SELECT last_given_at, last_receiver_id FROM rep_givers
+			WHERE guild_id = $1 AND user_id = $2
+			FOR UPDATE
+		`, m.GuildID, m.Author.ID).Scan(&lastGivenAt, &lastReceiverID)
+		if err != nil {
+			return err
+		}
+
+		if lastGivenAt.Valid {
+			if wait := repCooldown - time.Since(lastGivenAt.Time); wait > 0 {
+				return errRepCooldown{wait: wait}
+			}
+		}
+		if lastReceiverID.Valid && lastReceiverID.String == member.User.ID {
+			return errRepSameMember
+		}
+
+		_, err = tx.Exec(`
+			UPDATE rep_givers SET last_given_at = NOW(), last_receiver_id = $3
+			WHERE guild_id = $1 AND user_id = $2
+		`, m.GuildID, m.Author.ID, member.User.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO reputation (guild_id, giver_id, receiver_id, reason)
+			VALUES ($1, $2, $3, NULLIF($4, ''))
+		`, m.GuildID, m.Author.ID, member.User.ID, reason)
+		if err != nil {
+			return err
+		}
+
+		return tx.QueryRow(`
+			SELECT COUNT(*) FROM reputation WHERE guild_id = $1 AND receiver_id = $2
+		`, m.GuildID, member.User.ID).Scan(&total)
+	})
+
+	var cooldown errRepCooldown
+	if errors.As(err, &cooldown) {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("You can give reputation again in %s.", commands.FormatDuration(cooldown.wait)))
+		return
+	} else if errors.Is(err, errRepSameMember) {
+		s.ChannelMessageSend(m.ChannelID, "You gave your last rep to this member, give it to someone else first.")
+		return
+	} else if err != nil {
+		log.Printf("Error giving rep in guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	message := fmt.Sprintf("⭐ <@%s> gave <@%s> a reputation point! They now have %d rep.", m.Author.ID, member.User.ID, total)
+	if reason != "" {
+		message += fmt.Sprintf("\nReason: %s", reason)
+	}
+	for _, roleID := range awardRepRoles(b, s, m.GuildID, member, total) {
+		message += fmt.Sprintf("\n🎉 They reached a milestone and earned <@&%s>.", roleID)
+	}
+
+	s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content: message,
+		// Ping the receiver, never the roles
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{member.User.ID}},
+	})
+}
+
+// awardRepRoles gives the member every milestone role they have reached but don't
+// have yet and returns the roles that were added
+func awardRepRoles(b *bot.Bot, s *discordgo.Session, guildID string, member *discordgo.Member, total int) []string {
+	rows, err := b.Db.Query(`
+		SELECT role_id FROM rep_roles WHERE guild_id = $1 AND reps <= $2 ORDER BY reps
+	`, guildID, total)
+	if err != nil {
+		log.Printf("Error querying rep milestones: %v", err)
+		return nil
+	}
+	defer rows.Close()
+
+	has := make(map[string]bool, len(member.Roles))
+	for _, roleID := range member.Roles {
+		has[roleID] = true
+	}
+
+	var added []string
+	for rows.Next() {
+		var roleID string
+		if err := rows.Scan(&roleID); err != nil {
+			log.Printf("Error scanning rep milestone: %v", err)
+			continue
+		}
+		if has[roleID] {
+			continue
+		}
+		if err := s.GuildMemberRoleAdd(guildID, member.User.ID, roleID); err != nil {
+			log.Printf("Error adding rep role %s to %s: %v", roleID, member.User.ID, err)
+			continue
+		}
+		added = append(added, roleID)
+	}
+	return added
+}
+
+// memberRep returns the member's reputation and their position in the guild
+func memberRep(b *bot.Bot, guildID, userID string) (int, int, error) {
+	var reps, rank int
+	err := b.Db.QueryRow(`
+		WITH totals AS (
+			SELECT receiver_id, COUNT(*) AS reps FROM reputation WHERE guild_id = $1 GROUP BY receiver_id
+		)
+		SELECT COALESCE((SELECT reps FROM totals WHERE receiver_id = $2), 0),
+			(SELECT COUNT(*) + 1 FROM totals WHERE reps > COALESCE((SELECT reps FROM totals WHERE receiver_id = $2), 0))
+	`, guildID, userID).Scan(&reps, &rank)
+	return reps, rank, err
+}
+
+func showRep(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, target *discordgo.User) {
+	reps, rank, err := memberRep(b, m.GuildID, target.ID)
+	if err != nil {
+		log.Printf("Error querying rep: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:     fmt.Sprintf("%s's Reputation", target.Username),
+		Color:     0x5865f2,
+		Thumbnail: &discordgo.MessageEmbedThumbnail{URL: target.AvatarURL("")},
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Reputation", Value: strconv.Itoa(reps), Inline: true},
+			{Name: "Rank", Value: fmt.Sprintf("#%d", rank), Inline: true},
+		},
+	}
+
+	rows, err := b.Db.Query(`
+		SELECT giver_id, reason, created_at
+		FROM reputation
+		WHERE guild_id = $1 AND receiver_id = $2
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, m.GuildID, target.ID, recentRepsShown)
+	if err != nil {
+		log.Printf("Error querying recent reps: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var giverID string
+		var reason sql.NullString
+		var createdAt time.Time
+		if err := rows.Scan(&giverID, &reason, &createdAt); err != nil {
+			log.Printf("Error scanning recent reps: %v", err)
+			continue
+		}
+		line := fmt.Sprintf("<t:%d:d> from <@%s>", createdAt.Unix(), giverID)
+		if reason.Valid {
+			line += ": " + reason.String
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Latest Reps", Value: strings.Join(lines, "\n")})
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, embed)
+}
+
+func repLeaderboard(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, page int) {
+	rows, err := b.Db.Query(`
+		SELECT receiver_id, COUNT(*) AS reps
+		FROM reputation
+		WHERE guild_id = $1
+		GROUP BY receiver_id
+		ORDER BY reps DESC, receiver_id
+		LIMIT $2 OFFSET $3
+	`, m.GuildID, levelsPerPage, (page-1)*levelsPerPage)
+	if err != nil {
+		log.Printf("Error querying rep leaderboard: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving the leaderboard.")
+		return
+	}
+	defer rows.Close()
+
+	var lines []string
+	position := (page - 1) * levelsPerPage
+	for rows.Next() {
+		var userID string
+		var reps int
+		if err := rows.Scan(&userID, &reps); err != nil {
+			log.Printf("Error scanning rep leaderboard: %v", err)
+			continue
+		}
+		position++
+		lines = append(lines, fmt.Sprintf("%d. <@%s> - %d rep", position, userID, reps))
+	}
+
+	if len(lines) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "No one has received reputation on that page yet.")
+		return
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+		Title:       "Reputation Leaderboard",
+		Description: strings.Join(lines, "\n"),
+		Color:       0x5865f2,
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Page %d • .rep top <page>", page)},
+	})
+}
+
+// setRepMilestone sets or removes the role awarded at a reputation total
+func setRepMilestone(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Check if the user has administrator permissions
+	hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking admin status: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if !hasAdmin {
+		s.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command.")
+		return
+	}
+
+	if len(args) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Usage: .rep milestone <reps> <role|none>")
+		return
+	}
+	reps, err := strconv.Atoi(args[0])
+	if err != nil || reps < 1 || reps > maxRepMilestone {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("The milestone must be a number between 1 and %d.", maxRepMilestone))
+		return
+	}
+
+	var reply string
+	roleInput := strings.Join(args[1:], " ")
+	if strings.EqualFold(roleInput, "none") {
+		_, err = b.Db.Exec(`DELETE FROM rep_roles WHERE guild_id = $1 AND reps = $2`, m.GuildID, reps)
+		reply = fmt.Sprintf("%d rep no longer awards a role.", reps)
+	} else {
+		role, ferr := utils.FindRole(s, m.GuildID, roleInput)
+		if ferr != nil {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Role '%s' not found.", roleInput))
+			return
+		}
+		_, err = b.Db.Exec(`
+			INSERT INTO rep_roles (guild_id, reps, role_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (guild_id, reps) DO UPDATE SET role_id = EXCLUDED.role_id
+		`, m.GuildID, reps, role.ID)
+		reply = fmt.Sprintf("Members reaching %d rep now receive <@&%s>. Members already past it get it with their next rep.", reps, role.ID)
+	}
+
+	if err != nil {
+		log.Printf("Error setting rep milestone for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+	s.ChannelMessageSend(m.ChannelID, reply)
+}
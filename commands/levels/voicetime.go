@@ -0,0 +1,225 @@
+package levels
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/commands/economy"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const maxVoiceRewardPerMinute = 1000
+
+const voiceTimeUsage = "Usage:\n" +
+	"`.voicetime [@user]` - show time spent in voice\n" +
+	"`.voicetime top [page]` - show the voice time leaderboard\n" +
+	"Admins: `.voicetime rewards <xp per minute> <currency per minute>`"
+
+func init() {
+	commands.RegisterCommand("voicetime", VoiceTime, "vt")
+}
+
+// VoiceTime shows a member's time in voice, or the guild's voice time leaderboard, and
+// lets admins set voice rewards. Only active time counts: not AFK, not deafened and not
+// alone in the channel.
+func VoiceTime(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	if len(args) >= 2 {
+		switch strings.ToLower(args[1]) {
+		case "rewards":
+			setVoiceRewards(b, s, m, args[2:])
+			return
+		case "top":
+			page := 1
+			if len(args) >= 3 {
+				var err error
+				page, err = strconv.Atoi(args[2])
+				if err != nil || page < 1 {
+					s.ChannelMessageSend(m.ChannelID, "Usage: .voicetime top [page]")
+					return
+				}
+			}
+			voiceLeaderboard(b, s, m, page)
+			return
+		}
+	}
+
+	target := m.Author
+	if len(args) >= 2 {
+		targetUserID, err := utils.ExtractUserID(args[1])
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, voiceTimeUsage)
+			return
+		}
+
+		member, err := s.GuildMember(m.GuildID, targetUserID)
+		if err != nil || member == nil {
+			s.ChannelMessageSend(m.ChannelID, "mentioned user is not in this server.")
+			return
+		}
+		target = member.User
+	}
+
+	var seconds int64
+	var sessions, rank int
+	err := b.Db.QueryRow(`
+		SELECT seconds, sessions,
+			(SELECT COUNT(*) + 1 FROM voice_stats o WHERE o.guild_id = v.guild_id AND o.seconds > v.seconds)
+		FROM voice_stats v
+		WHERE guild_id = $1 AND user_id = $2
+	`, m.GuildID, target.ID).Scan(&seconds, &sessions, &rank)
+	if err == sql.ErrNoRows {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("%s hasn't spent any time in voice yet.", target.Username))
+		return
+	} else if err != nil {
+		log.Printf("Error querying voice time: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:     fmt.Sprintf("%s's Voice Time", target.Username),
+		Color:     0x5865f2,
+		Thumbnail: &discordgo.MessageEmbedThumbnail{URL: target.AvatarURL("")},
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Active Time", Value: commands.FormatDuration(time.Duration(seconds) * time.Second), Inline: true},
+			{Name: "Sessions", Value: strconv.Itoa(sessions), Inline: true},
+			{Name: "Rank", Value: fmt.Sprintf("#%d", rank), Inline: true},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: "Time AFK, deafened or alone in a channel doesn't count"},
+	}
+
+	var channelID string
+	var joinedAt time.Time
+	var activeSeconds int64
+	err = b.Db.QueryRow(`
+		SELECT channel_id, joined_at, active_seconds
+		FROM voice_sessions
+		WHERE guild_id = $1 AND user_id = $2 AND left_at IS NULL
+		ORDER BY joined_at DESC
+		LIMIT 1
+	`, m.GuildID, target.ID).Scan(&channelID, &joinedAt, &activeSeconds)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error querying current voice session: %v", err)
+	} else if err == nil {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: "Current Session",
+			Value: fmt.Sprintf("In <#%s> since <t:%d:R>, %s active", channelID, joinedAt.Unix(),
+				commands.FormatDuration(time.Duration(activeSeconds)*time.Second)),
+		})
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, embed)
+}
+
+func voiceLeaderboard(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, page int) {
+	rows, err := b.Db.Query(`
+		SELECT user_id, seconds
+		FROM voice_stats
+		WHERE guild_id = $1 AND seconds > 0
+		ORDER BY seconds DESC, user_id
+		LIMIT $2 OFFSET $3
+	`, m.GuildID, levelsPerPage, (page-1)*levelsPerPage)
+	if err != nil {
+		log.Printf("Error querying voice leaderboard: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving the leaderboard.")
+		return
+	}
+	defer rows.Close()
+
+	var lines []string
+	position := (page - 1) * levelsPerPage
+	for rows.Next() {
+		var userID string
+		var seconds int64
+		if err := rows.Scan(&userID, &seconds); err != nil {
+			log.Printf("Error scanning voice leaderboard: %v", err)
+			continue
+		}
+		position++
+		lines = append(lines, fmt.Sprintf("%d. <@%s> - %s", position, userID, commands.FormatDuration(time.Duration(seconds)*time.Second)))
+	}
+
+	if len(lines) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "No one has spent time in voice on that page yet.")
+		return
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+		Title:       "Voice Time Leaderboard",
+		Description: strings.Join(lines, "\n"),
+		Color:       0x5865f2,
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Page %d • .voicetime top <page>", page)},
+	})
+}
+
+// setVoiceRewards sets the XP and currency paid per active minute in voice
+func setVoiceRewards(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Check if the user has administrator permissions
+	hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking admin status: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if !hasAdmin {
+		s.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command.")
+		return
+	}
+
+	if len(args) == 0 {
+		config, err := utils.LoadVoiceConfig(b.Db, m.GuildID)
+		if err != nil {
+			log.Printf("Error loading voice config for guild %s: %v", m.GuildID, err)
+			s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+			return
+		}
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Each active minute in voice earns %d XP and %s.",
+			config.XPPerMinute, economy.GuildSettings(b, m.GuildID).Format(config.CoinsPerMinute)))
+		return
+	}
+
+	var xp, coins int64 = -1, -1
+	if len(args) >= 2 {
+		if n, err := strconv.ParseInt(args[0], 10, 64); err == nil {
+			xp = n
+		}
+		if n, err := strconv.ParseInt(args[1], 10, 64); err == nil {
+			coins = n
+		}
+	}
+	if xp < 0 || coins < 0 || xp > maxVoiceRewardPerMinute || coins > maxVoiceRewardPerMinute {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Usage: .voicetime rewards <xp per minute> <%s per minute>, between 0 and %d each",
+			strings.ToLower(economy.GuildSettings(b, m.GuildID).CurrencyName), maxVoiceRewardPerMinute))
+		return
+	}
+
+	_, err = b.Db.Exec(`
+		UPDATE guilds SET voice_xp_per_minute = $1, voice_coins_per_minute = $2 WHERE guild_id = $3
+	`, xp, coins, m.GuildID)
+	if err != nil {
+		log.Printf("Error updating voice rewards for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	reply := fmt.Sprintf("Each active minute in voice now earns %d XP and %s.", xp, economy.GuildSettings(b, m.GuildID).Format(coins))
+	if xp > 0 {
+		if config, err := utils.LoadXPConfig(b.Db, m.GuildID); err == nil && !config.Enabled {
+			reply += " Voice XP is only awarded while chat XP is enabled (`.level enable`)."
+		}
+	}
+	s.ChannelMessageSend(m.ChannelID, reply)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"DiscordBot/bot"
 	"DiscordBot/commands"
@@ -48,26 +49,49 @@ func Mute(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []s
 		reason = strings.Join(args[2:], " ")
 	}
 
-	// Get the Muted role
-	mutedRole, err := utils.GetMutedRole(s, m.GuildID)
-	if err != nil {
-		log.Printf("Error getting muted role: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred while getting the muted role.")
+	if _, err := MuteUser(b, s, m.GuildID, targetUser, 0); err != nil {
+		log.Printf("Error muting user: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while muting the user.")
 		return
 	}
 
-	// Add the Muted role to the user
-	err = s.GuildMemberRoleAdd(m.GuildID, targetUser, mutedRole.ID)
+	// Send the mute confirmation message
+	s.ChannelMessageSendEmbed(m.ChannelID, MuteEmbed(targetUser, reason, time.Time{}))
+}
+
+// MuteUser gives the member the Muted role. With a duration the timed role service
+// lifts the mute when it runs out, otherwise it lasts until .unmute. It returns when
+// the mute ends, or the zero time if it doesn't.
+func MuteUser(b *bot.Bot, s *discordgo.Session, guildID, userID string, duration time.Duration) (time.Time, error) {
+	mutedRole, err := utils.GetMutedRole(s, guildID)
 	if err != nil {
-		log.Printf("Error muting user: %v", err)
-		s.ChannelMessageSend(m.ChannelID, "An error occurred while muting the user.")
-		return
+		return time.Time{}, fmt.Errorf("error getting muted role: %w", err)
 	}
 
-	// Mute confirmation message
-	embed := &discordgo.MessageEmbed{
+	if err := s.GuildMemberRoleAdd(guildID, userID, mutedRole.ID); err != nil {
+		return time.Time{}, err
+	}
+
+	// A new mute replaces the length of an earlier one
+	if err := utils.ClearRoleExpiry(b.Db, guildID, userID, mutedRole.ID); err != nil {
+		return time.Time{}, err
+	}
+	if duration <= 0 {
+		return time.Time{}, nil
+	}
+	return utils.ExtendRoleExpiry(b.Db, guildID, userID, mutedRole.ID, duration, utils.RoleSourceMute)
+}
+
+// MuteEmbed builds the confirmation shown after a member is muted
+func MuteEmbed(userID, reason string, until time.Time) *discordgo.MessageEmbed {
+	description := fmt.Sprintf("Muted user <@%s>", userID)
+	if !until.IsZero() {
+		description += fmt.Sprintf(" until <t:%d:t>", until.Unix())
+	}
+
+	return &discordgo.MessageEmbed{
 		Title:       "User Muted",
-		Description: fmt.Sprintf("Muted user <@%s>", targetUser),
+		Description: description,
 		Color:       0xFF0000, // Red bar on the left
 		Fields: []*discordgo.MessageEmbedField{
 			{
@@ -77,7 +101,4 @@ func Mute(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []s
 			},
 		},
 	}
-
-	// Send the embed message
-	s.ChannelMessageSendEmbed(m.ChannelID, embed)
 }
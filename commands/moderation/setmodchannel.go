@@ -0,0 +1,53 @@
+package moderation
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	commands.RegisterCommand("setmodchannel", SetModChannel)
+}
+
+// SetModChannel sets the channel that message reports are forwarded to
+func SetModChannel(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Check if the user has administrator permissions
+	hasAdmin, err := utils.CheckAdminPermission(s, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking admin status: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if !hasAdmin {
+		s.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command.")
+		return
+	}
+
+	if len(args) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Usage: .setmodchannel <#channel>")
+		return
+	}
+
+	channelID := strings.TrimSuffix(strings.TrimPrefix(args[1], "<#"), ">")
+	channel, err := s.Channel(channelID)
+	if err != nil || channel.GuildID != m.GuildID {
+		s.ChannelMessageSend(m.ChannelID, "Invalid channel. Please mention a channel in this server.")
+		return
+	}
+
+	_, err = b.Db.Exec("UPDATE guilds SET mod_channel_id = $1 WHERE guild_id = $2", channelID, m.GuildID)
+	if err != nil {
+		log.Printf("Error setting mod channel for guild %s: %v", m.GuildID, err)
+		s.ChannelMessageSend(m.ChannelID, "Error setting the moderator channel. Please try again later.")
+		return
+	}
+
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Reports will now be sent to <#%s>.", channelID))
+}
@@ -57,5 +57,10 @@ func Unmute(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args [
 		return
 	}
 
+	// Drop the expiry of a timed mute so it isn't removed again later
+	if err := utils.ClearRoleExpiry(b.Db, m.GuildID, targetUser, mutedRole.ID); err != nil {
+		log.Printf("Error clearing mute expiry: %v", err)
+	}
+
 	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Unmuted user <@%s>", targetUser))
 }
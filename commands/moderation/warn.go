@@ -0,0 +1,100 @@
+package moderation
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	commands.RegisterCommand("warn", Warn, "w")
+}
+
+func Warn(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Check if the user has manage messages permission
+	hasManageMessages, err := utils.CheckManageMessagesPermission(s, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking manage messages permission: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred. Please try again.")
+		return
+	}
+
+	if !hasManageMessages {
+		s.ChannelMessageSend(m.ChannelID, "You do not have permission to use this command.")
+		return
+	}
+
+	// Check if the user provided at least a target user
+	if len(args) < 2 {
+		s.ChannelMessageSend(m.ChannelID, "Usage: .warn <@user> [reason]")
+		return
+	}
+
+	// Extract target user
+	targetUser, err := utils.ExtractUserID(args[1])
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, "Invalid user / use. Please use a proper mention (e.g., @username).")
+		return
+	}
+
+	// Set default reason
+	reason := "No reason provided - .warn used"
+	if len(args) >= 3 {
+		reason = strings.Join(args[2:], " ")
+	}
+
+	count, err := WarnUser(b, m.GuildID, targetUser, m.Author.ID, reason)
+	if err != nil {
+		log.Printf("Error warning user: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while warning the user.")
+		return
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, WarningEmbed(targetUser, reason, count))
+}
+
+// WarnUser records a warning and returns the user's total warnings in the guild
+func WarnUser(b *bot.Bot, guildID, userID, moderatorID, reason string) (int, error) {
+	_, err := b.Db.Exec(`
+		INSERT INTO warnings (guild_id, user_id, moderator_id, reason)
+		VALUES ($1, $2, $3, $4)
+	`, guildID, userID, moderatorID, reason)
+	if err != nil {
+		return 0, err
+	}
+
+	return WarningCount(b, guildID, userID)
+}
+
+// WarningCount returns how many warnings a user has in a guild
+func WarningCount(b *bot.Bot, guildID, userID string) (int, error) {
+	var count int
+	err := b.Db.QueryRow("SELECT COUNT(*) FROM warnings WHERE guild_id = $1 AND user_id = $2", guildID, userID).Scan(&count)
+	return count, err
+}
+
+// WarningEmbed builds the confirmation shown after a warning is recorded
+func WarningEmbed(userID, reason string, count int) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Title:       "User Warned",
+		Description: fmt.Sprintf("Warned user <@%s>", userID),
+		Color:       0xFFA500, // Orange bar on the left
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   "Reason",
+				Value:  reason,
+				Inline: false,
+			},
+			{
+				Name:   "Total Warnings",
+				Value:  fmt.Sprintf("%d", count),
+				Inline: true,
+			},
+		},
+	}
+}
@@ -213,6 +213,46 @@ func CreateCommandListPages(b *bot.Bot, s *discordgo.Session, m *discordgo.Messa
 	return pages, nil
 }
 
+// SendPaginated sends the first page and adds the arrow reactions when there is
+// more than one page. Only userID can flip through the pages.
+func SendPaginated(s *discordgo.Session, channelID, guildID, userID string, pages []*discordgo.MessageEmbed, startPage int) error {
+	if startPage < 0 || startPage >= len(pages) {
+		startPage = 0
+	}
+
+	msg, err := s.ChannelMessageSendEmbed(channelID, pages[startPage])
+	if err != nil {
+		return err
+	}
+
+	// If there's only one page, no need for pagination
+	if len(pages) <= 1 {
+		return nil
+	}
+
+	// Add reactions for pagination
+	err = s.MessageReactionAdd(msg.ChannelID, msg.ID, "⬅️")
+	if err != nil {
+		log.Printf("Error adding left reaction: %v", err)
+	}
+
+	err = s.MessageReactionAdd(msg.ChannelID, msg.ID, "➡️")
+	if err != nil {
+		log.Printf("Error adding right reaction: %v", err)
+	}
+
+	// Store pagination state
+	paginationManager.AddState(&PaginationState{
+		UserID:      userID,
+		MessageID:   msg.ID,
+		CurrentPage: startPage,
+		TotalPages:  len(pages),
+		Pages:       pages,
+		GuildID:     guildID,
+	})
+	return nil
+}
+
 // HandlePagination handles pagination reactions
 func HandlePagination(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
 	// Ignore bot reactions
@@ -16,8 +16,8 @@ func init() {
 	RegisterCommand("remindme", RemindMe)
 }
 
-// parseDuration converts "1d 2h 30m" into time.Duration
-func parseDuration(input string) (time.Duration, error) {
+// ParseDuration converts "1d 2h 30m" into time.Duration
+func ParseDuration(input string) (time.Duration, error) {
 	// Case-insensitive regex, allows spaces between units
 	re := regexp.MustCompile(`(?i)(\d+)\s*(s|sec|secs|m|min|mins|h|hr|hrs|d|day|days)`)
 	matches := re.FindAllStringSubmatch(input, -1)
@@ -45,8 +45,8 @@ func parseDuration(input string) (time.Duration, error) {
 	return total, nil
 }
 
-// formatDuration pretty-prints a duration like "1 day, 2 hours, 5 minutes"
-func formatDuration(d time.Duration) string {
+// FormatDuration pretty-prints a duration like "1 day, 2 hours, 5 minutes"
+func FormatDuration(d time.Duration) string {
 	days := int(d.Hours()) / 24
 	hours := int(d.Hours()) % 24
 	minutes := int(d.Minutes()) % 60
@@ -113,7 +113,7 @@ func RemindMe(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args
 	
 	if durationStr == "" {
 		// If no duration found, try to parse the first argument as duration
-		duration, err = parseDuration(args[1])
+		duration, err = ParseDuration(args[1])
 		if err != nil || duration <= 0 {
 			s.ChannelMessageSend(m.ChannelID,
 				"Invalid duration. Example: `10m`, `2h30m`, `1d 2h`")
@@ -121,7 +121,7 @@ func RemindMe(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args
 		}
 		message = strings.TrimSpace(strings.Join(args[2:], " "))
 	} else {
-		duration, err = parseDuration(durationStr)
+		duration, err = ParseDuration(durationStr)
 		if err != nil || duration <= 0 {
 			s.ChannelMessageSend(m.ChannelID,
 				"Invalid duration. Example: `10m`, `2h30m`, `1d 2h`")
@@ -151,5 +151,5 @@ func RemindMe(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args
 	// Confirm to user
 	s.ChannelMessageSend(m.ChannelID,
 		fmt.Sprintf("⏰ Reminder set for **%s** from now: %s",
-			formatDuration(duration), message))
+			FormatDuration(duration), message))
 }
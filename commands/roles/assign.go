@@ -0,0 +1,51 @@
+package roles
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	// ErrRoleTooHigh is returned when the role is not below the assigner's highest role
+	ErrRoleTooHigh = errors.New("role is equal to or higher than the assigner's highest role")
+	// ErrBotCannotAssign is returned when Discord rejects the change because of the bot's own position or permissions
+	ErrBotCannotAssign = errors.New("bot cannot assign this role")
+)
+
+// HighestRolePosition returns the position of the member's highest role in the guild
+func HighestRolePosition(guild *discordgo.Guild, member *discordgo.Member) int {
+	var highest int
+	for _, memberRoleID := range member.Roles {
+		for _, role := range guild.Roles {
+			if role.ID == memberRoleID && role.Position > highest {
+				highest = role.Position
+			}
+		}
+	}
+	return highest
+}
+
+// CheckAssignable returns ErrRoleTooHigh unless the role sits below the assigner's highest role
+func CheckAssignable(s *discordgo.Session, guild *discordgo.Guild, assignerID string, role *discordgo.Role) error {
+	assigner, err := s.GuildMember(guild.ID, assignerID)
+	if err != nil {
+		return err
+	}
+
+	if role.Position >= HighestRolePosition(guild, assigner) {
+		return ErrRoleTooHigh
+	}
+	return nil
+}
+
+// AddRole gives the member the role, returning ErrBotCannotAssign when the bot's
+// own role hierarchy or permissions prevent it
+func AddRole(s *discordgo.Session, guildID, userID string, role *discordgo.Role) error {
+	err := s.GuildMemberRoleAdd(guildID, userID, role.ID)
+	if err != nil && (strings.Contains(err.Error(), "Missing Permissions") || strings.Contains(err.Error(), "role hierarchy")) {
+		return ErrBotCannotAssign
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package roles
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	commands.RegisterCommand("myroles", MyRoles)
+}
+
+// MyRoles lists the caller's timed roles and how long each has left
+func MyRoles(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	// Ensure command is used in a guild
+	if m.GuildID == "" {
+		return // Don't respond to DMs
+	}
+
+	rows, err := b.Db.Query(`
+		SELECT role_id, expires_at
+		FROM timed_roles
+		WHERE guild_id = $1 AND user_id = $2 AND expires_at > NOW()
+		ORDER BY expires_at
+	`, m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Printf("Error querying timed roles: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while retrieving your roles.")
+		return
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var roleID string
+		var expiresAt time.Time
+		if err := rows.Scan(&roleID, &expiresAt); err != nil {
+			log.Printf("Error scanning timed role: %v", err)
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("<@&%s> - %s left (expires <t:%d:f>)",
+			roleID, commands.FormatDuration(time.Until(expiresAt)), expiresAt.Unix()))
+	}
+
+	if len(lines) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "You have no timed roles.")
+		return
+	}
+
+	s.ChannelMessageSendEmbed(m.ChannelID, &discordgo.MessageEmbed{
+		Title:       "Your Timed Roles",
+		Description: strings.Join(lines, "\n"),
+		Color:       0x00ff00,
+	})
+}
@@ -1,9 +1,11 @@
 package roles
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 	
 	"github.com/bwmarrin/discordgo"
 	"DiscordBot/utils"
@@ -17,7 +19,7 @@ func init() {
 
 func SetRole(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
 	if len(args) < 3 {
-		s.ChannelMessageSend(m.ChannelID, "Usage: .setrole/sr <@user> <role name or mention>")
+		s.ChannelMessageSend(m.ChannelID, "Usage: .setrole/sr <@user> <role name or mention> [duration:<7d>]")
 		return
 	}
 
@@ -34,6 +36,17 @@ func SetRole(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args
 		return
 	}
 
+	// An optional trailing "duration:7d" makes the role expire
+	var duration time.Duration
+	if last := strings.ToLower(args[len(args)-1]); len(args) > 3 && strings.HasPrefix(last, "duration:") {
+		duration, err = commands.ParseDuration(strings.TrimPrefix(last, "duration:"))
+		if err != nil || duration < time.Minute {
+			s.ChannelMessageSend(m.ChannelID, "Invalid duration. Use something like `30m`, `12h` or `7d`.")
+			return
+		}
+		args = args[:len(args)-1]
+	}
+
 	// Extract user mention and role name
 	userMention := args[1]
 	roleName := strings.Join(args[2:], " ")
@@ -80,42 +93,43 @@ func SetRole(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, args
 		return
 	}
 
-	// Fetch member executing the command
-	executor, err := s.GuildMember(m.GuildID, m.Author.ID)
-	if err != nil {
+	// Ensure executor can assign the target role
+	err = CheckAssignable(s, guild, m.Author.ID, targetRole)
+	if errors.Is(err, ErrRoleTooHigh) {
+		s.ChannelMessageSend(m.ChannelID, "You cannot assign a role equal to or higher than your highest role.")
+		return
+	} else if err != nil {
 		log.Printf("Error fetching executor: %v", err)
 		s.ChannelMessageSend(m.ChannelID, "An error occurred while verifying permissions.")
 		return
 	}
 
-	// Find the executor's highest role position
-	var executorHighestPosition int
-	for _, execRoleID := range executor.Roles {
-		for _, role := range guild.Roles {
-			if role.ID == execRoleID && role.Position > executorHighestPosition {
-				executorHighestPosition = role.Position
-			}
-		}
+	// Add the role to the target user
+	err = AddRole(s, m.GuildID, userID, targetRole)
+	if errors.Is(err, ErrBotCannotAssign) {
+		s.ChannelMessageSend(m.ChannelID, "I cannot add this role because it is higher than my highest role or due to missing permissions.")
+		return
+	} else if err != nil {
+		log.Printf("Error adding role: %v", err)
+		s.ChannelMessageSend(m.ChannelID, "An error occurred while adding the role to the user.")
+		return
 	}
 
-	// Ensure executor can assign the target role
-	if targetRole.Position >= executorHighestPosition {
-		s.ChannelMessageSend(m.ChannelID, "You cannot assign a role equal to or higher than your highest role.")
+	if duration == 0 {
+		// A permanent assignment replaces any earlier expiry
+		if err := utils.ClearRoleExpiry(b.Db, m.GuildID, userID, targetRole.ID); err != nil {
+			log.Printf("Error clearing role expiry: %v", err)
+		}
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Role '%s' added to user <@%s> successfully!", targetRole.Name, userID))
 		return
 	}
 
-	// Add the role to the target user
-	err = s.GuildMemberRoleAdd(m.GuildID, userID, targetRole.ID)
+	expiresAt, err := utils.ExtendRoleExpiry(b.Db, m.GuildID, userID, targetRole.ID, duration, utils.RoleSourceSetRole)
 	if err != nil {
-		// Check if the error is due to role hierarchy
-		if strings.Contains(err.Error(), "Missing Permissions") || strings.Contains(err.Error(), "role hierarchy") {
-			s.ChannelMessageSend(m.ChannelID, "I cannot add this role because it is higher than my highest role or due to missing permissions.")
-		} else {
-			log.Printf("Error adding role: %v", err)
-			s.ChannelMessageSend(m.ChannelID, "An error occurred while adding the role to the user.")
-		}
+		log.Printf("Error saving role expiry: %v", err)
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Role '%s' was added to <@%s>, but the expiry could not be saved. Please remove it manually.", targetRole.Name, userID))
 		return
 	}
 
-	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Role '%s' added to user <@%s> successfully!", targetRole.Name, userID))
+	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Role '%s' added to user <@%s> successfully! It expires <t:%d:R>.", targetRole.Name, userID, expiresAt.Unix()))
 }
\ No newline at end of file
@@ -0,0 +1,89 @@
+package slash
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const maxAutocompleteChoices = 25
+
+// choiceCache keeps slow-changing autocomplete values around, since Discord
+// sends an autocomplete request for every keystroke
+type choiceCache struct {
+	mu        sync.Mutex
+	values    []string
+	fetchedAt time.Time
+	ttl       time.Duration
+	fetch     func() ([]string, error)
+}
+
+func newChoiceCache(ttl time.Duration, fetch func() ([]string, error)) *choiceCache {
+	return &choiceCache{ttl: ttl, fetch: fetch}
+}
+
+// get returns the cached values, refreshing them once they are older than the ttl.
+// Stale values are returned if the refresh fails.
+func (c *choiceCache) get() ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.values != nil && time.Since(c.fetchedAt) < c.ttl {
+		return c.values, nil
+	}
+
+	values, err := c.fetch()
+	if err != nil {
+		if c.values != nil {
+			return c.values, nil
+		}
+		return nil, err
+	}
+
+	c.values = values
+	c.fetchedAt = time.Now()
+	return values, nil
+}
+
+// filterChoices returns the values matching the query, prefix matches first
+func filterChoices(values []string, query string) []*discordgo.ApplicationCommandOptionChoice {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	var prefixMatches, containsMatches []string
+	for _, value := range values {
+		lower := strings.ToLower(value)
+		switch {
+		case strings.HasPrefix(lower, query):
+			prefixMatches = append(prefixMatches, value)
+		case strings.Contains(lower, query):
+			containsMatches = append(containsMatches, value)
+		}
+	}
+	sort.Strings(prefixMatches)
+	sort.Strings(containsMatches)
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, maxAutocompleteChoices)
+	for _, value := range append(prefixMatches, containsMatches...) {
+		if len(choices) >= maxAutocompleteChoices {
+			break
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  value,
+			Value: value,
+		})
+	}
+	return choices
+}
+
+// findOption looks up an option by name
+func findOption(options []*discordgo.ApplicationCommandInteractionDataOption, name string) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, opt := range options {
+		if opt.Name == name {
+			return opt
+		}
+	}
+	return nil
+}
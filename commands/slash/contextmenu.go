@@ -0,0 +1,314 @@
+package slash
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/commands/economy"
+	"DiscordBot/commands/moderation"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+const contextMuteDuration = 10 * time.Minute
+
+func init() {
+	var manageMessages int64 = discordgo.PermissionManageMessages
+	guildOnly := false
+
+	RegisterCommand(&discordgo.ApplicationCommand{
+		Name:         "View balance",
+		Type:         discordgo.UserApplicationCommand,
+		DMPermission: &guildOnly,
+	}, ViewBalanceContext)
+	RegisterCommand(&discordgo.ApplicationCommand{
+		Name:                     "Warn",
+		Type:                     discordgo.UserApplicationCommand,
+		DefaultMemberPermissions: &manageMessages,
+		DMPermission:             &guildOnly,
+	}, WarnContext)
+	RegisterCommand(&discordgo.ApplicationCommand{
+		Name:                     "Mute 10m",
+		Type:                     discordgo.UserApplicationCommand,
+		DefaultMemberPermissions: &manageMessages,
+		DMPermission:             &guildOnly,
+	}, MuteContext)
+	RegisterCommand(&discordgo.ApplicationCommand{
+		Name:         "User info",
+		Type:         discordgo.UserApplicationCommand,
+		DMPermission: &guildOnly,
+	}, UserInfoContext)
+	RegisterCommand(&discordgo.ApplicationCommand{
+		Name:         "Report to moderators",
+		Type:         discordgo.MessageApplicationCommand,
+		DMPermission: &guildOnly,
+	}, ReportMessageContext)
+
+	commands.RegisterComponent("warn", WarnModalSubmit)
+	commands.RegisterComponent("mute", MuteModalSubmit)
+}
+
+// requireManageMessages applies the same check as the prefix moderation commands
+func requireManageMessages(s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+	hasManageMessages, err := utils.CheckManageMessagesPermission(s, i.GuildID, utils.InteractionUserID(i))
+	if err != nil {
+		log.Printf("Error checking manage messages permission: %v", err)
+		utils.RespondEphemeral(s, i, "An error occurred. Please try again.")
+		return false
+	}
+
+	if !hasManageMessages {
+		utils.RespondEphemeral(s, i, "You do not have permission to use this command.")
+		return false
+	}
+	return true
+}
+
+// ViewBalanceContext shows the target member's balance
+func ViewBalanceContext(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	targetID := i.ApplicationCommandData().TargetID
+
+	balance, err := b.Economy.Balance(i.GuildID, targetID)
+	if err != nil {
+		log.Printf("Error querying balance: %v", err)
+		utils.RespondEphemeral(s, i, "An error occurred. Please try again.")
+		return
+	}
+
+	utils.RespondEphemeral(s, i, fmt.Sprintf("<@%s>'s balance: %s", targetID, economy.GuildSettings(b, i.GuildID).Format(balance)))
+}
+
+// WarnContext opens a modal asking for the warning reason
+func WarnContext(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !requireManageMessages(s, i) {
+		return
+	}
+
+	targetID := i.ApplicationCommandData().TargetID
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: commands.CustomID("warn", targetID),
+			Title:    "Warn member",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:  "reason",
+							Label:     "Reason",
+							Style:     discordgo.TextInputParagraph,
+							Required:  false,
+							MaxLength: 500,
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Error opening warn modal: %v", err)
+	}
+}
+
+// WarnModalSubmit records the warning once the moderator submits the reason
+func WarnModalSubmit(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) < 1 || !requireManageMessages(s, i) {
+		return
+	}
+	targetID := args[0]
+
+	reason := strings.TrimSpace(utils.ModalValue(i.ModalSubmitData(), "reason"))
+	if reason == "" {
+		reason = "No reason provided - Warn used"
+	}
+
+	count, err := moderation.WarnUser(b, i.GuildID, targetID, utils.InteractionUserID(i), reason)
+	if err != nil {
+		log.Printf("Error warning user: %v", err)
+		utils.RespondEphemeral(s, i, "An error occurred while warning the user.")
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{moderation.WarningEmbed(targetID, reason, count)},
+		},
+	})
+}
+
+// MuteContext opens a modal asking for the mute reason
+func MuteContext(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !requireManageMessages(s, i) {
+		return
+	}
+
+	targetID := i.ApplicationCommandData().TargetID
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: commands.CustomID("mute", targetID),
+			Title:    "Mute member for 10 minutes",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:  "reason",
+							Label:     "Reason",
+							Style:     discordgo.TextInputParagraph,
+							Required:  false,
+							MaxLength: 500,
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Error opening mute modal: %v", err)
+	}
+}
+
+// MuteModalSubmit gives the member the Muted role for ten minutes, the same role .mute
+// uses, so .unmute can lift it early
+func MuteModalSubmit(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) < 1 || !requireManageMessages(s, i) {
+		return
+	}
+	targetID := args[0]
+
+	reason := strings.TrimSpace(utils.ModalValue(i.ModalSubmitData(), "reason"))
+	if reason == "" {
+		reason = "No reason provided - Mute 10m used"
+	}
+
+	until, err := moderation.MuteUser(b, s, i.GuildID, targetID, contextMuteDuration)
+	if err != nil {
+		log.Printf("Error muting user: %v", err)
+		utils.RespondEphemeral(s, i, "An error occurred while muting the user.")
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{moderation.MuteEmbed(targetID, reason, until)},
+		},
+	})
+}
+
+// UserInfoContext shows account and membership details for the target member
+func UserInfoContext(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	user := data.Resolved.Users[data.TargetID]
+	if user == nil {
+		utils.RespondEphemeral(s, i, "User not found.")
+		return
+	}
+
+	created, err := discordgo.SnowflakeTimestamp(user.ID)
+	if err != nil {
+		created = time.Time{}
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:     user.Username,
+		Color:     0x00ff00,
+		Thumbnail: &discordgo.MessageEmbedThumbnail{URL: user.AvatarURL("")},
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "ID", Value: user.ID, Inline: true},
+			{Name: "Account Created", Value: fmt.Sprintf("<t:%d:R>", created.Unix()), Inline: true},
+		},
+	}
+
+	member, err := s.GuildMember(i.GuildID, user.ID)
+	if err == nil {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: "Joined Server", Value: fmt.Sprintf("<t:%d:R>", member.JoinedAt.Unix()), Inline: true,
+		})
+
+		roles := "None"
+		if len(member.Roles) > 0 {
+			mentions := make([]string, 0, len(member.Roles))
+			for _, roleID := range member.Roles {
+				mentions = append(mentions, fmt.Sprintf("<@&%s>", roleID))
+			}
+			roles = strings.Join(mentions, " ")
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: fmt.Sprintf("Roles (%d)", len(member.Roles)), Value: roles,
+		})
+	}
+
+	if warnings, err := moderation.WarningCount(b, i.GuildID, user.ID); err == nil {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: "Warnings", Value: fmt.Sprintf("%d", warnings), Inline: true,
+		})
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// ReportMessageContext forwards a message and a jump link to the guild's mod channel
+func ReportMessageContext(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	message := data.Resolved.Messages[data.TargetID]
+	if message == nil {
+		utils.RespondEphemeral(s, i, "Message not found.")
+		return
+	}
+
+	var modChannelID string
+	err := b.Db.QueryRow("SELECT COALESCE(mod_channel_id::text, '') FROM guilds WHERE guild_id = $1", i.GuildID).Scan(&modChannelID)
+	if err != nil {
+		log.Printf("Error getting mod channel for guild %s: %v", i.GuildID, err)
+	}
+	if modChannelID == "" {
+		utils.RespondEphemeral(s, i, "No moderator channel is configured for this server. Ask an admin to use `.setmodchannel`.")
+		return
+	}
+
+	content := message.Content
+	if content == "" {
+		content = "*No text content*"
+	}
+	for _, attachment := range message.Attachments {
+		content += "\n" + attachment.URL
+	}
+	if runes := []rune(content); len(runes) > 4000 {
+		content = string(runes[:3997]) + "..."
+	}
+
+	jumpLink := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", i.GuildID, message.ChannelID, message.ID)
+	embed := &discordgo.MessageEmbed{
+		Title:       "Message Reported",
+		Description: content,
+		Color:       0xFF0000,
+		Timestamp:   message.Timestamp.Format(time.RFC3339),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Author", Value: fmt.Sprintf("<@%s>", message.Author.ID), Inline: true},
+			{Name: "Reported By", Value: fmt.Sprintf("<@%s>", utils.InteractionUserID(i)), Inline: true},
+			{Name: "Channel", Value: fmt.Sprintf("<#%s>", message.ChannelID), Inline: true},
+			{Name: "Jump to Message", Value: jumpLink},
+		},
+	}
+
+	_, err = s.ChannelMessageSendEmbed(modChannelID, embed)
+	if err != nil {
+		log.Printf("Error forwarding report to mod channel %s: %v", modChannelID, err)
+		utils.RespondEphemeral(s, i, "An error occurred while sending your report.")
+		return
+	}
+
+	utils.RespondEphemeral(s, i, "Thanks, the message has been reported to the moderators.")
+}
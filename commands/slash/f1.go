@@ -8,6 +8,13 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
+func init() {
+	RegisterCommand(&discordgo.ApplicationCommand{
+		Name:        "f1",
+		Description: "Toggle F1 weekend and session notifications",
+	}, F1SubscriptionToggle)
+}
+
 func F1SubscriptionToggle(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
 	// For slash commands, we need to get the user ID differently
 	userID := i.Member.User.ID
@@ -0,0 +1,115 @@
+package slash
+
+import (
+	"fmt"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands/sports/f1"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+var driverNameCache = newChoiceCache(time.Hour, func() ([]string, error) {
+	data, err := f1.FetchDriverStandings()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, list := range data.MRData.StandingsTable.StandingsLists {
+		for _, standing := range list.DriverStandings {
+			names = append(names, fmt.Sprintf("%s %s", standing.Driver.GivenName, standing.Driver.FamilyName))
+		}
+	}
+	return names, nil
+})
+
+var constructorNameCache = newChoiceCache(time.Hour, func() ([]string, error) {
+	data, err := f1.FetchConstructorStandings()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, list := range data.MRData.StandingsTable.StandingsLists {
+		for _, standing := range list.ConstructorStandings {
+			names = append(names, standing.Constructor.Name)
+		}
+	}
+	return names, nil
+})
+
+func init() {
+	RegisterCommand(&discordgo.ApplicationCommand{
+		Name:        "f1standing",
+		Description: "Show a driver's or constructor's championship position",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "driver",
+				Description: "Look up a driver",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "name",
+						Description:  "The driver's name or code",
+						Required:     true,
+						Autocomplete: true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "constructor",
+				Description: "Look up a constructor",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "name",
+						Description:  "The constructor's name",
+						Required:     true,
+						Autocomplete: true,
+					},
+				},
+			},
+		},
+	}, F1Standing)
+	RegisterAutocomplete("f1standing", F1StandingAutocomplete)
+}
+
+func F1Standing(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subcommand := i.ApplicationCommandData().Options[0]
+	name := subcommand.Options[0].StringValue()
+
+	var embed *discordgo.MessageEmbed
+	var err error
+	if subcommand.Name == "constructor" {
+		embed, err = f1.ConstructorStandingEmbed(name)
+	} else {
+		embed, err = f1.DriverStandingEmbed(name)
+	}
+	if err != nil {
+		utils.RespondEphemeral(s, i, err.Error())
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+		},
+	})
+}
+
+// F1StandingAutocomplete suggests current drivers or constructors depending on the subcommand
+func F1StandingAutocomplete(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, focused *discordgo.ApplicationCommandInteractionDataOption) []*discordgo.ApplicationCommandOptionChoice {
+	cache := driverNameCache
+	if i.ApplicationCommandData().Options[0].Name == "constructor" {
+		cache = constructorNameCache
+	}
+
+	names, err := cache.get()
+	if err != nil {
+		return nil
+	}
+	return filterChoices(names, focused.StringValue())
+}
@@ -0,0 +1,52 @@
+package slash
+
+import (
+	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	RegisterCommand(&discordgo.ApplicationCommand{
+		Name:        "help",
+		Description: "Show help for a command",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "command",
+				Description:  "The command to look up",
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+	}, Help)
+	RegisterAutocomplete("help", HelpAutocomplete)
+}
+
+func Help(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	name := i.ApplicationCommandData().Options[0].StringValue()
+
+	embed, err := commands.CommandHelpEmbed(b, i.GuildID, name)
+	if err != nil {
+		utils.RespondEphemeral(s, i, err.Error())
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// HelpAutocomplete suggests prefix command names and their aliases
+func HelpAutocomplete(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, focused *discordgo.ApplicationCommandInteractionDataOption) []*discordgo.ApplicationCommandOptionChoice {
+	names := make([]string, 0, len(commands.CommandDetails))
+	for name := range commands.CommandDetails {
+		names = append(names, name)
+	}
+	return filterChoices(names, focused.StringValue())
+}
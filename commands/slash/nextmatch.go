@@ -0,0 +1,56 @@
+package slash
+
+import (
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/commands/sports/epl"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Club names only change between seasons
+var clubNameCache = newChoiceCache(6*time.Hour, epl.ClubNames)
+
+func init() {
+	RegisterCommand(&discordgo.ApplicationCommand{
+		Name:        "nextmatch",
+		Description: "Show a Premier League club's next match",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "club",
+				Description:  "The club to look up",
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+	}, NextMatch)
+	RegisterAutocomplete("nextmatch", NextMatchAutocomplete)
+}
+
+func NextMatch(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	club := i.ApplicationCommandData().Options[0].StringValue()
+
+	embed, err := epl.ClubNextMatchEmbed(club)
+	if err != nil {
+		utils.RespondEphemeral(s, i, err.Error())
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+		},
+	})
+}
+
+// NextMatchAutocomplete suggests EPL club names from the FPL bootstrap data
+func NextMatchAutocomplete(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, focused *discordgo.ApplicationCommandInteractionDataOption) []*discordgo.ApplicationCommandOptionChoice {
+	names, err := clubNameCache.get()
+	if err != nil {
+		return nil
+	}
+	return filterChoices(names, focused.StringValue())
+}
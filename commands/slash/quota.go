@@ -9,6 +9,13 @@ import (
 	"QCheckWE"
 )
 
+func init() {
+	RegisterCommand(&discordgo.ApplicationCommand{
+		Name:        "wequota",
+		Description: "Check your WE internet quota",
+	}, WEQuota)
+}
+
 func WEQuota(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
 	// First try to get saved credentials
 	var landline, password string
@@ -18,7 +25,7 @@ func WEQuota(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "Please set up your credentials first using /setup",
+				Content: "Please set up your credentials first using /wesetup",
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		})
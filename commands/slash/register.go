@@ -1,112 +1,194 @@
 package slash
 
 import (
+	"fmt"
 	"log"
+	"sort"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-// registers and updates slash commands.
-func RegisterCommands(s *discordgo.Session, guildID string) {
-	log.Println("Registering slash commands...")
+// SyncOptions controls how registered commands are pushed to Discord
+type SyncOptions struct {
+	// GuildID registers the commands to a single guild, which applies instantly.
+	// Leave empty to register them globally.
+	GuildID string
+	// DryRun only prints the diff without changing anything on Discord
+	DryRun bool
+	// ClearGlobal removes the global commands when syncing to a guild, so the
+	// guild doesn't show every command twice
+	ClearGlobal bool
+}
+
+// CommandDiff describes the changes needed to bring Discord in line with the registry
+type CommandDiff struct {
+	Created   []string
+	Updated   []string
+	Deleted   []string
+	Unchanged []string
+	// Shadowed are commands also registered globally, which show up twice in the guild
+	Shadowed []string
+}
+
+// HasChanges reports whether syncing would change anything
+func (d *CommandDiff) HasChanges() bool {
+	return len(d.Created) > 0 || len(d.Updated) > 0 || len(d.Deleted) > 0
+}
 
-	// Fetch existing commands
-	existingCommands, err := s.ApplicationCommands(s.State.User.ID, guildID)
+// SyncCommands registers every command in the registry, removing commands that
+// are no longer registered. The diff is always logged before it is applied.
+func SyncCommands(s *discordgo.Session, opts SyncOptions) error {
+	scope := "globally"
+	if opts.GuildID != "" {
+		scope = fmt.Sprintf("to guild %s", opts.GuildID)
+	}
+	log.Printf("Syncing slash commands %s...", scope)
+
+	existingCommands, err := s.ApplicationCommands(s.State.User.ID, opts.GuildID)
 	if err != nil {
-		log.Fatalf("Could not fetch existing commands: %v", err)
-	}
-
-	// Define the commands we want
-	desiredCommands := []*discordgo.ApplicationCommand{
-		{
-			Name:        "f1",
-			Description: "Toggle F1 weekend and session notifications",
-		},
-		{
-			Name:        "wequota",
-			Description: "Check your WE internet quota",
-		},
-		{
-			Name:        "wesetup",
-			Description: "Set up your WE account credentials",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "landline",
-					Description: "Your WE landline number",
-					Required:    true,
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "password",
-					Description: "Your WE account password",
-					Required:    true,
-				},
-			},
-		},
-	}
-
-	// Create a map of existing commands for easy lookup
-	existingCommandMap := make(map[string]*discordgo.ApplicationCommand)
-	for _, cmd := range existingCommands {
-		existingCommandMap[cmd.Name] = cmd
-	}
-
-	// Process each desired command
-	for _, desiredCmd := range desiredCommands {
-		existingCmd, exists := existingCommandMap[desiredCmd.Name]
-		
-		if !exists {
-			// Command doesn't exist, create it
-			_, err := s.ApplicationCommandCreate(s.State.User.ID, guildID, desiredCmd)
-			if err != nil {
-				log.Fatalf("Cannot create slash command %q: %v", desiredCmd.Name, err)
-			}
-			log.Printf("Created command: %s", desiredCmd.Name)
-		} else {
-			// Command exists, check if it needs to be updated
-			if commandNeedsUpdate(existingCmd, desiredCmd) {
-				// Delete the existing command
-				err := s.ApplicationCommandDelete(s.State.User.ID, guildID, existingCmd.ID)
-				if err != nil {
-					log.Fatalf("Could not delete command %q: %v", existingCmd.Name, err)
-				}
-				log.Printf("Deleted outdated command: %s", existingCmd.Name)
-				
-				// Create the updated command
-				_, err = s.ApplicationCommandCreate(s.State.User.ID, guildID, desiredCmd)
-				if err != nil {
-					log.Fatalf("Cannot create updated slash command %q: %v", desiredCmd.Name, err)
-				}
-				log.Printf("Created updated command: %s", desiredCmd.Name)
-			} else {
-				// Command is up to date
-				log.Printf("Command %s is already up to date", desiredCmd.Name)
-			}
-		}
+		return fmt.Errorf("could not fetch existing commands: %w", err)
 	}
 
-	// Check for commands that should be removed (exist but are not desired)
-	for _, existingCmd := range existingCommands {
-		found := false
-		for _, desiredCmd := range desiredCommands {
-			if existingCmd.Name == desiredCmd.Name {
-				found = true
-				break
-			}
+	desiredCommands := desiredCommandDefinitions()
+	diff := diffCommands(existingCommands, desiredCommands)
+
+	var globalCommands []*discordgo.ApplicationCommand
+	if opts.GuildID != "" {
+		globalCommands, err = s.ApplicationCommands(s.State.User.ID, "")
+		if err != nil {
+			return fmt.Errorf("could not fetch global commands: %w", err)
 		}
-		
-		if !found {
-			// This command should be removed
-			err := s.ApplicationCommandDelete(s.State.User.ID, guildID, existingCmd.ID)
-			if err != nil {
-				log.Fatalf("Could not delete obsolete command %q: %v", existingCmd.Name, err)
-			}
-			log.Printf("Deleted obsolete command: %s", existingCmd.Name)
+		diff.Shadowed = shadowedCommands(globalCommands, desiredCommands)
+	}
+	logDiff(diff)
+
+	if opts.DryRun {
+		log.Println("Dry run enabled, slash commands were not changed.")
+		return nil
+	}
+
+	if opts.ClearGlobal && len(globalCommands) > 0 {
+		_, err = s.ApplicationCommandBulkOverwrite(s.State.User.ID, "", []*discordgo.ApplicationCommand{})
+		if err != nil {
+			return fmt.Errorf("could not clear global commands: %w", err)
 		}
+		log.Printf("Removed %d global commands.", len(globalCommands))
+	}
+
+	if !diff.HasChanges() {
+		log.Println("Slash commands are already up to date.")
+		return nil
+	}
+
+	// Bulk overwrite replaces the whole set, which also removes stale commands
+	_, err = s.ApplicationCommandBulkOverwrite(s.State.User.ID, opts.GuildID, desiredCommands)
+	if err != nil {
+		return fmt.Errorf("could not overwrite commands: %w", err)
 	}
 
 	log.Println("Slash commands registered successfully.")
+	return nil
+}
+
+// desiredCommandDefinitions returns the registered definitions sorted by name
+func desiredCommandDefinitions() []*discordgo.ApplicationCommand {
+	definitions := make([]*discordgo.ApplicationCommand, 0, len(CommandMap))
+	for _, cmd := range CommandMap {
+		definitions = append(definitions, cmd.Definition)
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return commandKey(definitions[i]) < commandKey(definitions[j])
+	})
+	return definitions
+}
+
+// shadowedCommands returns the desired commands that are also registered globally
+func shadowedCommands(global, desired []*discordgo.ApplicationCommand) []string {
+	globalKeys := make(map[string]bool, len(global))
+	for _, cmd := range global {
+		globalKeys[commandKey(cmd)] = true
+	}
+
+	var shadowed []string
+	for _, cmd := range desired {
+		if key := commandKey(cmd); globalKeys[key] {
+			shadowed = append(shadowed, key)
+		}
+	}
+	return shadowed
+}
+
+// diffCommands compares the commands on Discord with the desired commands
+func diffCommands(existing, desired []*discordgo.ApplicationCommand) *CommandDiff {
+	diff := &CommandDiff{}
+
+	existingCommandMap := make(map[string]*discordgo.ApplicationCommand)
+	for _, cmd := range existing {
+		existingCommandMap[commandKey(cmd)] = cmd
+	}
+
+	desiredKeys := make(map[string]bool)
+	for _, desiredCmd := range desired {
+		key := commandKey(desiredCmd)
+		desiredKeys[key] = true
+
+		existingCmd, exists := existingCommandMap[key]
+		switch {
+		case !exists:
+			diff.Created = append(diff.Created, key)
+		case commandNeedsUpdate(existingCmd, desiredCmd):
+			diff.Updated = append(diff.Updated, key)
+		default:
+			diff.Unchanged = append(diff.Unchanged, key)
+		}
+	}
+
+	for _, existingCmd := range existing {
+		key := commandKey(existingCmd)
+		if !desiredKeys[key] {
+			diff.Deleted = append(diff.Deleted, key)
+		}
+	}
+
+	return diff
+}
+
+// commandKey identifies a command; user, message and chat commands may share names
+func commandKey(cmd *discordgo.ApplicationCommand) string {
+	switch cmd.Type {
+	case discordgo.UserApplicationCommand:
+		return "user:" + cmd.Name
+	case discordgo.MessageApplicationCommand:
+		return "message:" + cmd.Name
+	default:
+		return "/" + cmd.Name
+	}
+}
+
+// chatCommandKey is the commandKey of the slash command with this name
+func chatCommandKey(name string) string {
+	return commandKey(&discordgo.ApplicationCommand{Name: name, Type: discordgo.ChatApplicationCommand})
+}
+
+func logDiff(diff *CommandDiff) {
+	for _, name := range diff.Created {
+		log.Printf("  + %s", name)
+	}
+	for _, name := range diff.Updated {
+		log.Printf("  ~ %s", name)
+	}
+	for _, name := range diff.Deleted {
+		log.Printf("  - %s", name)
+	}
+	log.Printf("Slash command diff: %d to create, %d to update, %d to delete, %d unchanged",
+		len(diff.Created), len(diff.Updated), len(diff.Deleted), len(diff.Unchanged))
+	if len(diff.Shadowed) > 0 {
+		for _, name := range diff.Shadowed {
+			log.Printf("  ! %s", name)
+		}
+		log.Printf("%d commands are also registered globally and show up twice in this guild. Set SLASH_CLEAR_GLOBAL=true to remove the global ones.",
+			len(diff.Shadowed))
+	}
 }
 
 // commandNeedsUpdate checks if an existing command needs to be updated
@@ -115,23 +197,61 @@ func commandNeedsUpdate(existing, desired *discordgo.ApplicationCommand) bool {
 	if existing.Description != desired.Description {
 		return true
 	}
-	
+
+	if !samePermissions(existing.DefaultMemberPermissions, desired.DefaultMemberPermissions) {
+		return true
+	}
+
+	// Discord treats a missing DM permission as allowed
+	existingDM := existing.DMPermission == nil || *existing.DMPermission
+	desiredDM := desired.DMPermission == nil || *desired.DMPermission
+	if existingDM != desiredDM {
+		return true
+	}
+
+	return optionsNeedUpdate(existing.Options, desired.Options)
+}
+
+// optionsNeedUpdate compares option lists, including subcommand options and choices
+func optionsNeedUpdate(existing, desired []*discordgo.ApplicationCommandOption) bool {
 	// Check options count
-	if len(existing.Options) != len(desired.Options) {
+	if len(existing) != len(desired) {
 		return true
 	}
-	
+
 	// Check each option
-	for i, existingOption := range existing.Options {
-		desiredOption := desired.Options[i]
-		
+	for i, existingOption := range existing {
+		desiredOption := desired[i]
+
 		if existingOption.Type != desiredOption.Type ||
 			existingOption.Name != desiredOption.Name ||
 			existingOption.Description != desiredOption.Description ||
-			existingOption.Required != desiredOption.Required {
+			existingOption.Required != desiredOption.Required ||
+			existingOption.Autocomplete != desiredOption.Autocomplete {
+			return true
+		}
+
+		if len(existingOption.Choices) != len(desiredOption.Choices) {
+			return true
+		}
+		for j, choice := range existingOption.Choices {
+			if choice.Name != desiredOption.Choices[j].Name ||
+				fmt.Sprint(choice.Value) != fmt.Sprint(desiredOption.Choices[j].Value) {
+				return true
+			}
+		}
+
+		if optionsNeedUpdate(existingOption.Options, desiredOption.Options) {
 			return true
 		}
 	}
-	
+
 	return false
 }
+
+func samePermissions(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,108 @@
+package slash
+
+import (
+	"log"
+
+	"DiscordBot/bot"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+// CommandFunc handles an application command interaction
+type CommandFunc func(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate)
+
+// AutocompleteFunc returns the choices to suggest for the focused option
+type AutocompleteFunc func(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, focused *discordgo.ApplicationCommandInteractionDataOption) []*discordgo.ApplicationCommandOptionChoice
+
+// Command pairs an application command definition with its handlers
+type Command struct {
+	Definition   *discordgo.ApplicationCommand
+	Handler      CommandFunc
+	Autocomplete AutocompleteFunc
+}
+
+// CommandMap holds every registered application command by commandKey, so a
+// context menu command can share its name with a slash command
+var CommandMap = make(map[string]*Command)
+
+// RegisterCommand adds an application command to the registry. The definition
+// is what gets synced to Discord by SyncCommands.
+func RegisterCommand(definition *discordgo.ApplicationCommand, handler CommandFunc) {
+	key := commandKey(definition)
+	if _, exists := CommandMap[key]; exists {
+		log.Fatalf("application command %q is registered twice", key)
+	}
+	CommandMap[key] = &Command{
+		Definition: definition,
+		Handler:    handler,
+	}
+}
+
+// RegisterAutocomplete attaches an autocomplete handler to a registered slash command.
+// Options that should use it must set Autocomplete in their definition.
+func RegisterAutocomplete(name string, handler AutocompleteFunc) {
+	cmd, ok := CommandMap[chatCommandKey(name)]
+	if !ok {
+		log.Fatalf("cannot register autocomplete for unknown command %q", name)
+	}
+	cmd.Autocomplete = handler
+}
+
+// HandleCommand dispatches an application command interaction to its handler
+func HandleCommand(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	key := commandKey(&discordgo.ApplicationCommand{Name: data.Name, Type: data.CommandType})
+	cmd, ok := CommandMap[key]
+	if !ok {
+		log.Printf("Received unknown application command %q", key)
+		utils.RespondEphemeral(s, i, "This command is no longer available.")
+		return
+	}
+
+	cmd.Handler(b, s, i)
+}
+
+// HandleAutocomplete answers an autocomplete interaction with the choices
+// returned by the command's autocomplete handler
+func HandleAutocomplete(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	// Only slash commands have options to autocomplete
+	cmd, ok := CommandMap[chatCommandKey(data.Name)]
+	if !ok || cmd.Autocomplete == nil {
+		return
+	}
+
+	focused := focusedOption(data.Options)
+	if focused == nil {
+		return
+	}
+
+	choices := cmd.Autocomplete(b, s, i, focused)
+	// Discord accepts at most 25 choices
+	if len(choices) > maxAutocompleteChoices {
+		choices = choices[:maxAutocompleteChoices]
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+	if err != nil {
+		log.Printf("Error responding to autocomplete for %s: %v", data.Name, err)
+	}
+}
+
+// focusedOption finds the option the user is typing in, including inside subcommands
+func focusedOption(options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, opt := range options {
+		if opt.Focused {
+			return opt
+		}
+		if found := focusedOption(opt.Options); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package slash
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"DiscordBot/bot"
+	"DiscordBot/utils"
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	RegisterCommand(&discordgo.ApplicationCommand{
+		Name:        "cancelreminder",
+		Description: "Cancel one of your pending reminders",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "reminder",
+				Description:  "The reminder to cancel",
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+	}, CancelReminder)
+	RegisterAutocomplete("cancelreminder", CancelReminderAutocomplete)
+}
+
+func CancelReminder(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	userID := utils.InteractionUserID(i)
+
+	reminderID, err := strconv.ParseInt(i.ApplicationCommandData().Options[0].StringValue(), 10, 64)
+	if err != nil {
+		utils.RespondEphemeral(s, i, "Please pick a reminder from the list.")
+		return
+	}
+
+	// Only the owner's own pending reminders can be cancelled
+	result, err := b.Db.Exec(`
+		DELETE FROM reminders
+		WHERE reminder_id = $1 AND user_id = $2 AND sent = FALSE
+	`, reminderID, userID)
+	if err != nil {
+		log.Printf("Error cancelling reminder %d for user %s: %v", reminderID, userID, err)
+		utils.RespondEphemeral(s, i, "An error occurred while cancelling your reminder.")
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		utils.RespondEphemeral(s, i, "Reminder not found. It may have already been sent.")
+		return
+	}
+
+	utils.RespondEphemeral(s, i, "Reminder cancelled.")
+}
+
+// CancelReminderAutocomplete suggests the caller's own pending reminders
+func CancelReminderAutocomplete(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, focused *discordgo.ApplicationCommandInteractionDataOption) []*discordgo.ApplicationCommandOptionChoice {
+	rows, err := b.Db.Query(`
+		SELECT reminder_id, message, remind_at
+		FROM reminders
+		WHERE user_id = $1 AND sent = FALSE AND message ILIKE '%' || $2 || '%'
+		ORDER BY remind_at
+		LIMIT $3
+	`, utils.InteractionUserID(i), focused.StringValue(), maxAutocompleteChoices)
+	if err != nil {
+		log.Printf("Error querying reminders for autocomplete: %v", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for rows.Next() {
+		var reminderID int64
+		var message string
+		var remindAt time.Time
+		if err := rows.Scan(&reminderID, &message, &remindAt); err != nil {
+			log.Printf("Error scanning reminder: %v", err)
+			continue
+		}
+
+		// Choice names are limited to 100 characters
+		name := fmt.Sprintf("%s UTC - %s", remindAt.UTC().Format("Jan 2 15:04"), strings.TrimSpace(message))
+		if runes := []rune(name); len(runes) > 100 {
+			name = string(runes[:97]) + "..."
+		}
+
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  name,
+			Value: strconv.FormatInt(reminderID, 10),
+		})
+	}
+	return choices
+}
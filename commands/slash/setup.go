@@ -2,28 +2,87 @@ package slash
 
 import (
 	"log"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
 	"DiscordBot/bot"
+	"DiscordBot/commands"
+	"DiscordBot/utils"
 	"QCheckWE"
 )
 
+func init() {
+	RegisterCommand(&discordgo.ApplicationCommand{
+		Name:        "wesetup",
+		Description: "Set up your WE account credentials",
+	}, WEAccountSetup)
+
+	commands.RegisterComponent("wesetup", WEAccountSetupSubmit)
+}
+
+// WEAccountSetup opens a modal for the credentials, so they never show up in
+// the client's command history like slash command options do
 func WEAccountSetup(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	options := i.ApplicationCommandData().Options
-	landline := options[0].StringValue()
-	password := options[1].StringValue()
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: commands.CustomID("wesetup"),
+			Title:    "WE account setup",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "landline",
+							Label:       "Landline number",
+							Style:       discordgo.TextInputShort,
+							Placeholder: "02XXXXXXXX",
+							Required:    true,
+							MinLength:   10,
+							MaxLength:   10,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID: "password",
+							Label:    "Password",
+							Style:    discordgo.TextInputShort,
+							Required: true,
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Error opening WE setup modal: %v", err)
+	}
+}
+
+// WEAccountSetupSubmit validates the submitted credentials and saves them
+func WEAccountSetupSubmit(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	data := i.ModalSubmitData()
+	landline := strings.TrimSpace(utils.ModalValue(data, "landline"))
+	password := utils.ModalValue(data, "password")
+
+	// Checking the quota can take longer than Discord's response window
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		log.Printf("Error deferring WE setup response: %v", err)
+		return
+	}
 
 	// test the credentials first
 	checker, err := QCheckWE.NewWeQuotaChecker(landline, password)
 	if err != nil {
 		log.Printf("Error creating WE Quota Checker: %v", err)
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Invalid credentials",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
+		editResponse(s, i, "Invalid credentials")
 		return
 	}
 
@@ -31,13 +90,7 @@ func WEAccountSetup(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCr
 	_, err = checker.CheckQuota()
 	if err != nil {
 		log.Printf("Error checking quota: %v", err)
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Invalid credentials",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
+		editResponse(s, i, "Invalid credentials")
 		return
 	}
 
@@ -47,25 +100,23 @@ func WEAccountSetup(b *bot.Bot, s *discordgo.Session, i *discordgo.InteractionCr
                 VALUES ($1, $2, $3)
                 ON CONFLICT (user_id)
                 DO UPDATE SET landline = $2, password = $3
-            `, i.Member.User.ID, landline, password)
+            `, utils.InteractionUserID(i), landline, password)
 
 	if err != nil {
 		log.Printf("Error saving credentials: %v", err)
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Error saving credentials",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
+		editResponse(s, i, "Error saving credentials")
 		return
 	}
 
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: "Credentials saved successfully! You can now use /quota",
-			Flags:   discordgo.MessageFlagsEphemeral,
-		},
+	editResponse(s, i, "Credentials saved successfully! You can now use /wequota")
+}
+
+// editResponse replaces the content of a deferred interaction response
+func editResponse(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
 	})
+	if err != nil {
+		log.Printf("Error editing interaction response: %v", err)
+	}
 }
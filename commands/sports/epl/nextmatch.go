@@ -1,6 +1,7 @@
 package epl
 
 import (
+	"errors"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -84,16 +85,26 @@ func showUpcomingFixtures(b *bot.Bot, s *discordgo.Session, m *discordgo.Message
 }
 
 func showClubNextMatch(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, clubName string) {
-	fixtures, err := fetchFixtures()
+	embed, err := ClubNextMatchEmbed(clubName)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error fetching EPL fixtures. Please try again later.")
+		s.ChannelMessageSend(m.ChannelID, err.Error())
 		return
 	}
 
+	s.ChannelMessageSendEmbed(m.ChannelID, embed)
+}
+
+// ClubNextMatchEmbed builds the embed for a club's next fixture. Returned errors
+// are safe to show to users.
+func ClubNextMatchEmbed(clubName string) (*discordgo.MessageEmbed, error) {
+	fixtures, err := fetchFixtures()
+	if err != nil {
+		return nil, errors.New("Error fetching EPL fixtures. Please try again later.")
+	}
+
 	teamsData, err := getTeamsData()
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error fetching team data. Please try again later.")
-		return
+		return nil, errors.New("Error fetching team data. Please try again later.")
 	}
 
 	teamMap := make(map[int]string)
@@ -124,14 +135,12 @@ func showClubNextMatch(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCre
 	}
 
 	if nextMatch == nil {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("No upcoming match found for club: %s", clubName))
-		return
+		return nil, fmt.Errorf("No upcoming match found for club: %s", clubName)
 	}
 
 	matchTime, err := time.Parse("2006-01-02T15:04:05Z", nextMatch.KickoffTime)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error parsing match time. Please try again later.")
-		return
+		return nil, errors.New("Error parsing match time. Please try again later.")
 	}
 	// Convert to Unix timestamp for Discord's timestamp formatting
 	unixTimestamp := matchTime.Unix()
@@ -159,7 +168,21 @@ func showClubNextMatch(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCre
 		},
 	}
 
-	s.ChannelMessageSendEmbed(m.ChannelID, embed)
+	return embed, nil
+}
+
+// ClubNames returns the names of every EPL club from the FPL bootstrap data
+func ClubNames() ([]string, error) {
+	teamsData, err := getTeamsData()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(teamsData))
+	for _, t := range teamsData {
+		names = append(names, t.Name)
+	}
+	return names, nil
 }
 
 func fetchFixtures() ([]FPLFixture, error) {
@@ -1,7 +1,9 @@
 package f1
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
 	"DiscordBot/bot"
@@ -57,4 +59,56 @@ func getConstructorsChampionship(b *bot.Bot, s *discordgo.Session, m *discordgo.
 	}
 
 	s.ChannelMessageSendEmbed(m.ChannelID, embed)
-}
\ No newline at end of file
+}
+
+// ConstructorStandingEmbed builds the embed for a constructor's championship
+// position. Returned errors are safe to show to users.
+func ConstructorStandingEmbed(constructorQuery string) (*discordgo.MessageEmbed, error) {
+	data, err := FetchConstructorStandings()
+	if err != nil {
+		return nil, errors.New("Error fetching constructor standings")
+	}
+
+	if len(data.MRData.StandingsTable.StandingsLists) == 0 {
+		return nil, errors.New("No constructor standings data available")
+	}
+
+	standings := data.MRData.StandingsTable.StandingsLists[0].ConstructorStandings
+	constructorQuery = strings.ToLower(constructorQuery)
+
+	for _, standing := range standings {
+		if !strings.Contains(strings.ToLower(standing.Constructor.Name), constructorQuery) {
+			continue
+		}
+
+		color, ok := TeamColors[standing.Constructor.Name]
+		if !ok {
+			color = 0xFF0000 // Red color for F1
+		}
+
+		embed := &discordgo.MessageEmbed{
+			Title: fmt.Sprintf("🏎️ %s", standing.Constructor.Name),
+			Color: color,
+			Fields: []*discordgo.MessageEmbedField{
+				{
+					Name:   "Position",
+					Value:  standing.Position,
+					Inline: true,
+				},
+				{
+					Name:   "Points",
+					Value:  standing.Points,
+					Inline: true,
+				},
+				{
+					Name:   "Wins",
+					Value:  standing.Wins,
+					Inline: true,
+				},
+			},
+		}
+		return embed, nil
+	}
+
+	return nil, fmt.Errorf("Constructor '%s' not found in the championship standings", constructorQuery)
+}
@@ -1,6 +1,7 @@
 package f1
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -71,15 +72,25 @@ func getDriversChampionship(b *bot.Bot, s *discordgo.Session, m *discordgo.Messa
 
 // getSpecificDriverStanding fetches and displays a specific driver's championship position
 func getSpecificDriverStanding(b *bot.Bot, s *discordgo.Session, m *discordgo.MessageCreate, driverQuery string) {
-	data, err := FetchDriverStandings()
+	embed, err := DriverStandingEmbed(driverQuery)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, "Error fetching driver standings")
+		s.ChannelMessageSend(m.ChannelID, err.Error())
 		return
 	}
 
+	s.ChannelMessageSendEmbed(m.ChannelID, embed)
+}
+
+// DriverStandingEmbed builds the embed for a driver's championship position,
+// matched by name or code. Returned errors are safe to show to users.
+func DriverStandingEmbed(driverQuery string) (*discordgo.MessageEmbed, error) {
+	data, err := FetchDriverStandings()
+	if err != nil {
+		return nil, errors.New("Error fetching driver standings")
+	}
+
 	if len(data.MRData.StandingsTable.StandingsLists) == 0 {
-		s.ChannelMessageSend(m.ChannelID, "No driver standings data available")
-		return
+		return nil, errors.New("No driver standings data available")
 	}
 
 	standings := data.MRData.StandingsTable.StandingsLists[0].DriverStandings
@@ -111,8 +122,7 @@ func getSpecificDriverStanding(b *bot.Bot, s *discordgo.Session, m *discordgo.Me
 	}
 
 	if foundStanding == nil {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Driver '%s' not found in the championship standings", driverQuery))
-		return
+		return nil, fmt.Errorf("Driver '%s' not found in the championship standings", driverQuery)
 	}
 
 	driverName := fmt.Sprintf("%s %s", foundStanding.Driver.GivenName, foundStanding.Driver.FamilyName)
@@ -140,5 +150,5 @@ func getSpecificDriverStanding(b *bot.Bot, s *discordgo.Session, m *discordgo.Me
 		},
 	}
 
-	s.ChannelMessageSendEmbed(m.ChannelID, embed)
-}
\ No newline at end of file
+	return embed, nil
+}
@@ -13,7 +13,9 @@ import (
 	"DiscordBot/utils"
 	
 	_ "DiscordBot/commands/admin"
+	"DiscordBot/commands/casino"
 	_ "DiscordBot/commands/economy"
+	_ "DiscordBot/commands/levels"
 	_ "DiscordBot/commands/moderation"
 	_ "DiscordBot/commands/roles"
 	
@@ -33,6 +35,9 @@ func main() {
 		log.Fatal(err)
 	}
 
+	levelService := utils.NewLevelService(bot.Db, bot.Client)
+	voiceService := utils.NewVoiceService(bot.Db, bot.Client, levelService)
+
 	bot.Client.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
 		if m.Author.ID == s.State.User.ID {
 			return
@@ -61,6 +66,8 @@ func main() {
 		// Permissions are now checked directly with Discord
 
 		if !strings.HasPrefix(m.Content, ".") {
+			// Chat messages earn XP, commands don't
+			levelService.HandleMessage(m)
 			return
 		}
 
@@ -113,27 +120,40 @@ func main() {
 	})
 
 	bot.Client.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		if i.Type != discordgo.InteractionApplicationCommand {
+		// Buttons, selects and modals are routed by their custom ID prefix
+		if i.Type == discordgo.InteractionMessageComponent || i.Type == discordgo.InteractionModalSubmit {
+			commands.HandleComponent(bot, s, i)
 			return
 		}
 
-		switch i.ApplicationCommandData().Name {
-		case "wequota":
-			slash.WEQuota(bot, s, i)
-		case "wesetup":
-			slash.WEAccountSetup(bot, s, i)
-		case "f1":
-			slash.F1SubscriptionToggle(bot, s, i)
+		switch i.Type {
+		case discordgo.InteractionApplicationCommand:
+			slash.HandleCommand(bot, s, i)
+		case discordgo.InteractionApplicationCommandAutocomplete:
+			slash.HandleAutocomplete(bot, s, i)
 		}
 	})
 
+	// Track voice sessions, including members already in voice when a guild becomes available
+	bot.Client.AddHandler(voiceService.HandleVoiceStateUpdate)
+	bot.Client.AddHandler(voiceService.HandleGuildCreate)
+
 	err = bot.Client.Open()
 	if err != nil {
 		log.Fatalf("error opening connection to Discord: %v", err)
 	}
 
-	// Register slash commands
-	slash.RegisterCommands(bot.Client, "")
+	// Register slash commands. DEV_GUILD_ID registers them to a single guild
+	// instantly instead of globally, SLASH_DRY_RUN only prints the diff and
+	// SLASH_CLEAR_GLOBAL removes global commands that would show up twice in that guild.
+	err = slash.SyncCommands(bot.Client, slash.SyncOptions{
+		GuildID:     os.Getenv("DEV_GUILD_ID"),
+		DryRun:      os.Getenv("SLASH_DRY_RUN") == "true",
+		ClearGlobal: os.Getenv("SLASH_CLEAR_GLOBAL") == "true",
+	})
+	if err != nil {
+		log.Printf("Error registering slash commands: %v", err)
+	}
 
 	// Start F1 Notifier
 	f1Notifier := f1.NewF1Notifier(bot.Client, bot.Db)
@@ -143,6 +163,33 @@ func main() {
 	reminderService := utils.NewReminderService(bot.Db, bot.Client)
 	go reminderService.Start()
 
+	// Start Timed Role Service
+	timedRoleService := utils.NewTimedRoleService(bot.Db, bot.Client)
+	go timedRoleService.Start()
+
+	// Start Bank Interest Service
+	bankInterestService := utils.NewBankInterestService(bot.Db)
+	go bankInterestService.Start()
+
+	// Start Escrow Service
+	escrowService := utils.NewEscrowService(bot.Db)
+	go escrowService.Start()
+
+	// Start Blackjack Expiry Service
+	blackjackExpiryService := casino.NewBlackjackExpiryService(bot)
+	go blackjackExpiryService.Start()
+
+	// Start Lottery Service
+	lotteryService := utils.NewLotteryService(bot.Db, bot.Client)
+	go lotteryService.Start()
+
+	// Start Role Income Service
+	roleIncomeService := utils.NewRoleIncomeService(bot.Db, bot.Client)
+	go roleIncomeService.Start()
+
+	// Start Voice Service
+	go voiceService.Start()
+
 	defer bot.Client.Close()
 
 	log.Println("Bot is now running. Press CTRL-C to exit.")
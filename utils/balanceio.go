@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"database/sql"
+	"sort"
+)
+
+// MemberBalances is a member's wallet and bank balance, as exported and imported
+type MemberBalances struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username,omitempty"` // informational, ignored on import
+	Balance  int64  `json:"balance"`
+	Bank     int64  `json:"bank_balance"`
+}
+
+// ExportBalances returns every member of the guild with their balances, richest first
+func (es *EconomyService) ExportBalances(guildID string) ([]MemberBalances, error) {
+	rows, err := es.db.Query(`
+		SELECT gm.user_id, COALESCE(u.username, ''), gm.balance, gm.bank_balance
+		FROM guild_members gm
+		LEFT JOIN users u ON u.user_id = gm.user_id
+		WHERE gm.guild_id = $1
+		ORDER BY gm.balance + gm.bank_balance DESC, gm.user_id
+	`, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []MemberBalances
+	for rows.Next() {
+		var member MemberBalances
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Balance, &member.Bank); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// SetBalances sets each listed member's wallet and bank to the given values,
+// recording the differences in the ledger under reason. Members that aren't listed
+// keep their balances. It returns the number of members whose balances changed.
+func SetBalances(tx *sql.Tx, guildID, actorID, reason string, members []MemberBalances) (int, error) {
+	// Lock rows in a fixed order so this can't deadlock with transfers
+	sorted := append([]MemberBalances(nil), members...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].UserID < sorted[j].UserID })
+
+	changed := 0
+	for _, member := range sorted {
+		balance, bank, err := LockAccounts(tx, guildID, member.UserID)
+		if err != nil {
+			return 0, err
+		}
+		if balance == member.Balance && bank == member.Bank {
+			continue
+		}
+
+		if balance != member.Balance {
+			_, err := ApplyChange(tx, BalanceChange{
+				GuildID: guildID, UserID: member.UserID, Amount: member.Balance - balance,
+				Reason: reason, ActorID: actorID,
+			})
+			if err != nil {
+				return 0, err
+			}
+		}
+		if bank != member.Bank {
+			_, err := ApplyChange(tx, BalanceChange{
+				GuildID: guildID, UserID: member.UserID, Amount: member.Bank - bank,
+				Reason: reason, Account: AccountBank, ActorID: actorID,
+			})
+			if err != nil {
+				return 0, err
+			}
+		}
+		changed++
+	}
+	return changed, nil
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"database/sql"
+	"log"
+	"math"
+	"time"
+)
+
+// BankInterestService pays each guild's daily bank interest
+type BankInterestService struct {
+	db *sql.DB
+}
+
+func NewBankInterestService(db *sql.DB) *BankInterestService {
+	return &BankInterestService{db: db}
+}
+
+func (bs *BankInterestService) Start() {
+	log.Println("Starting bank interest service...")
+	ticker := time.NewTicker(1 * time.Hour) // Guilds are paid once a day, checked hourly
+	defer ticker.Stop()
+
+	for range ticker.C {
+		bs.payDueInterest()
+	}
+}
+
+func (bs *BankInterestService) payDueInterest() {
+	rows, err := bs.db.Query(`
+		SELECT guild_id
+		FROM guilds
+		WHERE bank_interest_rate > 0
+			AND (bank_interest_paid_at IS NULL OR bank_interest_paid_at <= NOW() - INTERVAL '1 day')
+	`)
+	if err != nil {
+		log.Printf("Error querying guilds due for interest: %v", err)
+		return
+	}
+
+	var guildIDs []string
+	for rows.Next() {
+		var guildID string
+		if err := rows.Scan(&guildID); err != nil {
+			log.Printf("Error scanning guild: %v", err)
+			continue
+		}
+		guildIDs = append(guildIDs, guildID)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating over guilds: %v", err)
+	}
+	rows.Close()
+
+	for _, guildID := range guildIDs {
+		paid, err := bs.payGuildInterest(guildID)
+		if err != nil {
+			log.Printf("Error paying interest for guild %s: %v", guildID, err)
+			continue
+		}
+		if paid > 0 {
+			log.Printf("Paid bank interest to %d members in guild %s", paid, guildID)
+		}
+	}
+}
+
+// payGuildInterest pays one day of interest to every bank account in the guild.
+// Claiming the run and paying it share a transaction, so a day is never paid twice.
+func (bs *BankInterestService) payGuildInterest(guildID string) (int, error) {
+	paid := 0
+	tx, err := bs.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var rate float64
+	var interestCap int64
+	err = tx.QueryRow(`
+		UPDATE guilds
+		SET bank_interest_paid_at = NOW()
+		WHERE guild_id = $1
+			AND bank_interest_rate > 0
+			AND (bank_interest_paid_at IS NULL OR bank_interest_paid_at <= NOW() - INTERVAL '1 day')
+		RETURNING bank_interest_rate, COALESCE(bank_interest_cap, 0)
+	`, guildID).Scan(&rate, &interestCap)
+	if err == sql.ErrNoRows {
+		// Another run already paid this guild
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT user_id, bank_balance FROM guild_members
+		WHERE guild_id = $1 AND bank_balance > 0
+		ORDER BY user_id
+		FOR UPDATE
+	`, guildID)
+	if err != nil {
+		return 0, err
+	}
+
+	banks := make(map[string]int64)
+	var ids []string
+	for rows.Next() {
+		var userID string
+		var bank int64
+		if err := rows.Scan(&userID, &bank); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		banks[userID] = bank
+		ids = append(ids, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		interest := int64(math.Floor(float64(banks[id]) * rate / 100))
+		if interestCap > 0 && interest > interestCap {
+			interest = interestCap
+		}
+		if interest <= 0 {
+			continue
+		}
+
+		_, err := ApplyChange(tx, BalanceChange{
+			GuildID: guildID, UserID: id, Amount: interest,
+			Reason: ReasonInterest, Account: AccountBank,
+		})
+		if err != nil {
+			return 0, err
+		}
+		paid++
+	}
+
+	return paid, tx.Commit()
+}
@@ -0,0 +1,626 @@
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ErrInsufficientFunds is returned when a change would take a balance below zero
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrTransferCapReached is returned when a transfer would take the sender past the guild's daily cap
+var ErrTransferCapReached = errors.New("daily transfer cap reached")
+
+// Ledger reasons recorded in the transactions table
+const (
+	ReasonWork            = "work"
+	ReasonFlip            = "flip"
+	ReasonTransfer        = "transfer"
+	ReasonAdminAdd        = "admin_add"
+	ReasonAdminTake       = "admin_take"
+	ReasonStartingBalance = "starting_balance"
+	ReasonAdminSet        = "admin_set"
+	ReasonAdminGrant      = "admin_grant"
+	ReasonAdminReset      = "admin_reset"
+	ReasonShopPurchase    = "shop_purchase"
+	ReasonBankDeposit     = "bank_deposit"
+	ReasonBankWithdraw    = "bank_withdraw"
+	ReasonInterest        = "interest"
+	ReasonCasinoBet       = "casino_bet"
+	ReasonCasinoPayout    = "casino_payout"
+	ReasonEscrowRefund    = "escrow_refund"
+	ReasonDuelStake       = "duel_stake"
+	ReasonDuelPayout      = "duel_payout"
+	ReasonTransferTax     = "transfer_tax"
+	ReasonLotteryTicket   = "lottery_ticket"
+	ReasonLotteryPrize    = "lottery_prize"
+	ReasonRoleIncome      = "role_income"
+	ReasonAdminImport     = "admin_import"
+	ReasonSeasonReset     = "season_reset"
+	ReasonVoice           = "voice"
+)
+
+// Accounts a member holds. Each ledger row records which one changed.
+const (
+	AccountWallet = "wallet"
+	AccountBank   = "bank"
+)
+
+// accountColumns maps an account to its guild_members column
+var accountColumns = map[string]string{
+	AccountWallet: "balance",
+	AccountBank:   "bank_balance",
+}
+
+// EconomySettings holds a guild's economy configuration
+type EconomySettings struct {
+	CurrencyName           string
+	CurrencyEmoji          string
+	StartingBalance        int64
+	WorkMinPayout          int64
+	WorkMaxPayout          int64
+	WorkCooldownHours      int
+	FlipMaxBet             int64   // 0 means no limit
+	BankInterestRate       float64 // percent of the bank balance paid daily
+	BankInterestCap        int64   // most interest a member earns per day, 0 means no limit
+	StreakBonus            float64 // extra work payout percent per consecutive work after the first
+	StreakMaxBonus         float64 // highest streak bonus percent
+	StreakGraceHours       int     // how long after the cooldown ends a streak survives
+	TransferTax            float64 // percent of each transfer paid into the guild treasury
+	TransferDailyCap       int64   // most a member can send in 24 hours, 0 means no limit
+	TransferMinMemberDays  int     // days both members must have been in the guild
+	TransferMinAccountDays int     // days since both Discord accounts were created
+	TransferConfirmAbove   int64   // transfers of at least this much need a confirmation, 0 means never
+}
+
+// DefaultEconomySettings matches the column defaults in the guilds table
+func DefaultEconomySettings() *EconomySettings {
+	return &EconomySettings{
+		CurrencyName:           "Coins",
+		CurrencyEmoji:          "🪙",
+		StartingBalance:        0,
+		WorkMinPayout:          65,
+		WorkMaxPayout:          650,
+		WorkCooldownHours:      24,
+		FlipMaxBet:             0,
+		BankInterestRate:       0,
+		BankInterestCap:        0,
+		StreakBonus:            5,
+		StreakMaxBonus:         50,
+		StreakGraceHours:       12,
+		TransferTax:            0,
+		TransferDailyCap:       0,
+		TransferMinMemberDays:  0,
+		TransferMinAccountDays: 0,
+		TransferConfirmAbove:   0,
+	}
+}
+
+// Format renders an amount with the guild's currency, e.g. "🪙 150 Coins"
+func (es *EconomySettings) Format(amount int64) string {
+	if es.CurrencyEmoji == "" {
+		return fmt.Sprintf("%d %s", amount, es.CurrencyName)
+	}
+	return fmt.Sprintf("%s %d %s", es.CurrencyEmoji, amount, es.CurrencyName)
+}
+
+// BalanceChange describes a single balance mutation and the ledger row it produces
+type BalanceChange struct {
+	GuildID        string
+	UserID         string
+	Amount         int64
+	Reason         string
+	Account        string // AccountWallet or AccountBank, empty means the wallet
+	ActorID        string // who caused the change, empty for the system
+	CounterpartyID string // the other member involved, if any
+}
+
+// EconomyService performs every balance mutation inside a transaction and
+// writes a ledger row for each one, so balances can be reconciled from the ledger
+type EconomyService struct {
+	db *sql.DB
+}
+
+func NewEconomyService(db *sql.DB) *EconomyService {
+	return &EconomyService{db: db}
+}
+
+// EnsureMember creates or refreshes the global user row and makes sure the
+// user is a member of the guild's economy
+func (es *EconomyService) EnsureMember(guildID string, user *discordgo.User) error {
+	_, err := es.db.Exec(`
+		INSERT INTO users (user_id, username, avatar)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET
+			username = EXCLUDED.username,
+			avatar = EXCLUDED.avatar,
+			updated_at = NOW()
+	`, user.ID, user.Username, user.AvatarURL(""))
+	if err != nil {
+		return fmt.Errorf("error upserting user: %w", err)
+	}
+
+	return es.WithTx(func(tx *sql.Tx) error {
+		return ensureGuildMember(tx, guildID, user.ID)
+	})
+}
+
+// Settings returns the guild's economy settings, falling back to the defaults
+func (es *EconomyService) Settings(guildID string) (*EconomySettings, error) {
+	settings := DefaultEconomySettings()
+	err := es.db.QueryRow(`
+		SELECT COALESCE(currency_name, $2), COALESCE(currency_emoji, $3), COALESCE(starting_balance, $4),
+			COALESCE(work_min_payout, $5), COALESCE(work_max_payout, $6),
+			COALESCE(work_cooldown_hours, $7), COALESCE(flip_max_bet, $8),
+			COALESCE(bank_interest_rate, $9), COALESCE(bank_interest_cap, $10),
+			COALESCE(streak_bonus, $11), COALESCE(streak_max_bonus, $12), COALESCE(streak_grace_hours, $13),
+			COALESCE(transfer_tax, $14), COALESCE(transfer_daily_cap, $15), COALESCE(transfer_min_member_days, $16),
+			COALESCE(transfer_min_account_days, $17), COALESCE(transfer_confirm_above, $18)
+		FROM guilds
+		WHERE guild_id = $1
+	`, guildID, settings.CurrencyName, settings.CurrencyEmoji, settings.StartingBalance,
+		settings.WorkMinPayout, settings.WorkMaxPayout, settings.WorkCooldownHours, settings.FlipMaxBet,
+		settings.BankInterestRate, settings.BankInterestCap,
+		settings.StreakBonus, settings.StreakMaxBonus, settings.StreakGraceHours,
+		settings.TransferTax, settings.TransferDailyCap, settings.TransferMinMemberDays,
+		settings.TransferMinAccountDays, settings.TransferConfirmAbove,
+	).Scan(&settings.CurrencyName, &settings.CurrencyEmoji, &settings.StartingBalance,
+		&settings.WorkMinPayout, &settings.WorkMaxPayout, &settings.WorkCooldownHours, &settings.FlipMaxBet,
+		&settings.BankInterestRate, &settings.BankInterestCap,
+		&settings.StreakBonus, &settings.StreakMaxBonus, &settings.StreakGraceHours,
+		&settings.TransferTax, &settings.TransferDailyCap, &settings.TransferMinMemberDays,
+		&settings.TransferMinAccountDays, &settings.TransferConfirmAbove)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	return settings, err
+}
+
+// ensureGuildMember adds the member to the guild's economy, crediting the
+// guild's starting balance through the ledger when they are new
+func ensureGuildMember(tx *sql.Tx, guildID, userID string) error {
+	_, err := tx.Exec(`INSERT INTO users (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`, userID)
+	if err != nil {
+		return fmt.Errorf("error ensuring user: %w", err)
+	}
+
+	var startingBalance int64
+	err = tx.QueryRow(`
+		INSERT INTO guild_members (guild_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (guild_id, user_id) DO NOTHING
+		RETURNING (SELECT COALESCE(starting_balance, 0) FROM guilds WHERE guild_id = $1)
+	`, guildID, userID).Scan(&startingBalance)
+	if err == sql.ErrNoRows {
+		// Already a member
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error ensuring guild member: %w", err)
+	}
+
+	if startingBalance > 0 {
+		_, err = ApplyChange(tx, BalanceChange{
+			GuildID: guildID,
+			UserID:  userID,
+			Amount:  startingBalance,
+			Reason:  ReasonStartingBalance,
+		})
+	}
+	return err
+}
+
+// Balance returns a member's balance, or 0 if they have never used the economy
+func (es *EconomyService) Balance(guildID, userID string) (int64, error) {
+	var balance int64
+	err := es.db.QueryRow(`
+		SELECT COALESCE((SELECT balance FROM guild_members WHERE guild_id = $1 AND user_id = $2), 0)
+	`, guildID, userID).Scan(&balance)
+	return balance, err
+}
+
+// BankBalance returns a member's bank balance, or 0 if they have never used the economy
+func (es *EconomyService) BankBalance(guildID, userID string) (int64, error) {
+	var balance int64
+	err := es.db.QueryRow(`
+		SELECT COALESCE((SELECT bank_balance FROM guild_members WHERE guild_id = $1 AND user_id = $2), 0)
+	`, guildID, userID).Scan(&balance)
+	return balance, err
+}
+
+// TreasuryBalance returns the coins the guild has collected in transfer tax
+func (es *EconomyService) TreasuryBalance(guildID string) (int64, error) {
+	var balance int64
+	err := es.db.QueryRow(`
+		SELECT COALESCE((SELECT treasury_balance FROM guilds WHERE guild_id = $1), 0)
+	`, guildID).Scan(&balance)
+	return balance, err
+}
+
+// WithTx runs fn inside a transaction, committing if it returns nil
+func (es *EconomyService) WithTx(fn func(tx *sql.Tx) error) error {
+	tx, err := es.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// LockBalance makes sure the member row exists, locks it for the rest of the
+// transaction and returns the current balance
+func LockBalance(tx *sql.Tx, guildID, userID string) (int64, error) {
+	if err := ensureGuildMember(tx, guildID, userID); err != nil {
+		return 0, err
+	}
+
+	var balance int64
+	err := tx.QueryRow(`
+		SELECT balance FROM guild_members
+		WHERE guild_id = $1 AND user_id = $2
+		FOR UPDATE
+	`, guildID, userID).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("error locking balance: %w", err)
+	}
+	return balance, nil
+}
+
+// LockBank is LockBalance for the member's bank account
+func LockBank(tx *sql.Tx, guildID, userID string) (int64, error) {
+	_, bank, err := LockAccounts(tx, guildID, userID)
+	return bank, err
+}
+
+// LockAccounts is LockBalance returning both the wallet and the bank balance
+func LockAccounts(tx *sql.Tx, guildID, userID string) (int64, int64, error) {
+	if err := ensureGuildMember(tx, guildID, userID); err != nil {
+		return 0, 0, err
+	}
+
+	var balance, bank int64
+	err := tx.QueryRow(`
+		SELECT balance, bank_balance FROM guild_members
+		WHERE guild_id = $1 AND user_id = $2
+		FOR UPDATE
+	`, guildID, userID).Scan(&balance, &bank)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error locking balance: %w", err)
+	}
+	return balance, bank, nil
+}
+
+// ApplyChange updates a locked member's balance and records the ledger row.
+// The member must already be locked with LockBalance in the same transaction.
+func ApplyChange(tx *sql.Tx, change BalanceChange) (int64, error) {
+	if change.Account == "" {
+		change.Account = AccountWallet
+	}
+	column, ok := accountColumns[change.Account]
+	if !ok {
+		return 0, fmt.Errorf("unknown account %q", change.Account)
+	}
+
+	var before, after int64
+	// column comes from accountColumns, never from user input
+	err := tx.QueryRow(fmt.Sprintf(`
+		UPDATE guild_members
+		SET %[1]s = %[1]s + $1
+		WHERE guild_id = $2 AND user_id = $3
+		RETURNING %[1]s - $1, %[1]s
+	`, column), change.Amount, change.GuildID, change.UserID).Scan(&before, &after)
+	if err != nil {
+		return 0, fmt.Errorf("error updating balance: %w", err)
+	}
+
+	if after < 0 {
+		return 0, ErrInsufficientFunds
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO transactions (guild_id, user_id, account, amount, balance_before, balance_after, reason, actor_id, counterparty_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, change.GuildID, change.UserID, change.Account, change.Amount, before, after, change.Reason,
+		nullableID(change.ActorID), nullableID(change.CounterpartyID))
+	if err != nil {
+		return 0, fmt.Errorf("error writing ledger entry: %w", err)
+	}
+	return after, nil
+}
+
+// Adjust applies a single balance change atomically and returns the new balance
+func (es *EconomyService) Adjust(change BalanceChange) (int64, error) {
+	var after int64
+	err := es.WithTx(func(tx *sql.Tx) error {
+		if _, err := LockBalance(tx, change.GuildID, change.UserID); err != nil {
+			return err
+		}
+
+		var err error
+		after, err = ApplyChange(tx, change)
+		return err
+	})
+	return after, err
+}
+
+// Mutate locks a member's balance and lets decide compute the change from it.
+// decide may return an error to abort without changing anything.
+// The balances before and after the change are returned.
+func (es *EconomyService) Mutate(change BalanceChange, decide func(balance int64) (int64, error)) (int64, int64, error) {
+	var before, after int64
+	err := es.WithTx(func(tx *sql.Tx) error {
+		var err error
+		before, err = LockBalance(tx, change.GuildID, change.UserID)
+		if err != nil {
+			return err
+		}
+
+		change.Amount, err = decide(before)
+		if err != nil {
+			return err
+		}
+
+		after, err = ApplyChange(tx, change)
+		return err
+	})
+	return before, after, err
+}
+
+// TransferRules are the guild limits checked inside the transfer transaction
+type TransferRules struct {
+	TaxPercent float64 // share of the amount that goes to the guild treasury
+	DailyCap   int64   // most a member can send in 24 hours, 0 means no limit
+}
+
+// TransferResult describes a completed (or capped) transfer
+type TransferResult struct {
+	FromAfter int64
+	ToAfter   int64
+	Tax       int64 // taken from the amount and paid into the treasury
+	SentToday int64 // sent by the sender in the last 24 hours, before this transfer
+}
+
+// Transfer moves coins between two members atomically. The sender pays amount,
+// the recipient receives amount minus the tax and the tax goes to the guild treasury.
+func (es *EconomyService) Transfer(guildID, fromID, toID string, amount int64, reason string, rules TransferRules) (*TransferResult, error) {
+	var result *TransferResult
+	err := es.WithTx(func(tx *sql.Tx) error {
+		var err error
+		result, err = TransferTx(tx, guildID, fromID, toID, amount, reason, rules)
+		return err
+	})
+	return result, err
+}
+
+// TransferTx is Transfer inside the caller's transaction, e.g. to consume a
+// confirmation together with the transfer
+func TransferTx(tx *sql.Tx, guildID, fromID, toID string, amount int64, reason string, rules TransferRules) (*TransferResult, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("transfer amount must be positive")
+	}
+	if fromID == toID {
+		return nil, fmt.Errorf("cannot transfer to the same member")
+	}
+
+	result := &TransferResult{Tax: int64(math.Floor(float64(amount) * rules.TaxPercent / 100))}
+
+	// Lock both rows in a fixed order so concurrent transfers can't deadlock
+	ids := []string{fromID, toID}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if _, err := LockBalance(tx, guildID, id); err != nil {
+			return result, err
+		}
+	}
+
+	// The sender's row is locked, so concurrent transfers can't both fit under the cap
+	if rules.DailyCap > 0 {
+		err := tx.QueryRow(`
+			SELECT COALESCE(-SUM(amount), 0)
+			FROM transactions
+			WHERE guild_id = $1 AND user_id = $2 AND reason IN ($3, $4) AND amount < 0
+				AND created_at > NOW() - INTERVAL '24 hours'
+		`, guildID, fromID, reason, ReasonTransferTax).Scan(&result.SentToday)
+		if err != nil {
+			return result, fmt.Errorf("error summing transfers: %w", err)
+		}
+		if result.SentToday+amount > rules.DailyCap {
+			return result, ErrTransferCapReached
+		}
+	}
+
+	net := amount - result.Tax
+	var err error
+	result.FromAfter, err = ApplyChange(tx, BalanceChange{
+		GuildID: guildID, UserID: fromID, Amount: -net,
+		Reason: reason, ActorID: fromID, CounterpartyID: toID,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if result.Tax > 0 {
+		result.FromAfter, err = ApplyChange(tx, BalanceChange{
+			GuildID: guildID, UserID: fromID, Amount: -result.Tax,
+			Reason: ReasonTransferTax, ActorID: fromID, CounterpartyID: toID,
+		})
+		if err != nil {
+			return result, err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE guilds SET treasury_balance = COALESCE(treasury_balance, 0) + $1 WHERE guild_id = $2
+		`, result.Tax, guildID)
+		if err != nil {
+			return result, fmt.Errorf("error paying treasury: %w", err)
+		}
+	}
+
+	result.ToAfter, err = ApplyChange(tx, BalanceChange{
+		GuildID: guildID, UserID: toID, Amount: net,
+		Reason: reason, ActorID: fromID, CounterpartyID: fromID,
+	})
+	return result, err
+}
+
+// MoveBetweenAccounts moves coins between a member's wallet and bank in one
+// transaction. decide receives the balance of the from account and returns the
+// amount to move. The from and to balances after the move are returned.
+func (es *EconomyService) MoveBetweenAccounts(guildID, userID, from, to string, decide func(available int64) (int64, error)) (int64, int64, error) {
+	reason := ReasonBankDeposit
+	if from == AccountBank {
+		reason = ReasonBankWithdraw
+	}
+
+	var fromAfter, toAfter int64
+	err := es.WithTx(func(tx *sql.Tx) error {
+		wallet, err := LockBalance(tx, guildID, userID)
+		if err != nil {
+			return err
+		}
+		bank, err := LockBank(tx, guildID, userID)
+		if err != nil {
+			return err
+		}
+
+		available := wallet
+		if from == AccountBank {
+			available = bank
+		}
+		amount, err := decide(available)
+		if err != nil {
+			return err
+		}
+		if amount <= 0 {
+			return fmt.Errorf("amount must be positive")
+		}
+
+		fromAfter, err = ApplyChange(tx, BalanceChange{
+			GuildID: guildID, UserID: userID, Amount: -amount,
+			Reason: reason, Account: from, ActorID: userID,
+		})
+		if err != nil {
+			return err
+		}
+
+		toAfter, err = ApplyChange(tx, BalanceChange{
+			GuildID: guildID, UserID: userID, Amount: amount,
+			Reason: reason, Account: to, ActorID: userID,
+		})
+		return err
+	})
+	return fromAfter, toAfter, err
+}
+
+// Grant credits amount to every member in userIDs in a single transaction
+func (es *EconomyService) Grant(guildID string, userIDs []string, amount int64, reason, actorID string) error {
+	ids := append([]string(nil), userIDs...)
+	// Lock rows in a fixed order so concurrent grants can't deadlock
+	sort.Strings(ids)
+
+	return es.WithTx(func(tx *sql.Tx) error {
+		for _, id := range ids {
+			if _, err := LockBalance(tx, guildID, id); err != nil {
+				return err
+			}
+			_, err := ApplyChange(tx, BalanceChange{
+				GuildID: guildID, UserID: id, Amount: amount,
+				Reason: reason, ActorID: actorID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ResetGuild puts every member of the guild back on the starting balance, empties
+// bank accounts and clears work cooldowns. It returns the number of members whose
+// balances changed.
+func (es *EconomyService) ResetGuild(guildID, actorID string) (int, error) {
+	changed := 0
+	err := es.WithTx(func(tx *sql.Tx) error {
+		var startingBalance int64
+		err := tx.QueryRow(`
+			SELECT COALESCE((SELECT starting_balance FROM guilds WHERE guild_id = $1), 0)
+		`, guildID).Scan(&startingBalance)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(`
+			SELECT user_id, balance, bank_balance FROM guild_members
+			WHERE guild_id = $1
+			ORDER BY user_id
+			FOR UPDATE
+		`, guildID)
+		if err != nil {
+			return err
+		}
+
+		balances := make(map[string]int64)
+		banks := make(map[string]int64)
+		var ids []string
+		for rows.Next() {
+			var userID string
+			var balance, bank int64
+			if err := rows.Scan(&userID, &balance, &bank); err != nil {
+				rows.Close()
+				return err
+			}
+			balances[userID] = balance
+			banks[userID] = bank
+			ids = append(ids, userID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if balances[id] == startingBalance && banks[id] == 0 {
+				continue
+			}
+			if balances[id] != startingBalance {
+				_, err := ApplyChange(tx, BalanceChange{
+					GuildID: guildID, UserID: id, Amount: startingBalance - balances[id],
+					Reason: ReasonAdminReset, ActorID: actorID,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			if banks[id] != 0 {
+				_, err := ApplyChange(tx, BalanceChange{
+					GuildID: guildID, UserID: id, Amount: -banks[id],
+					Reason: ReasonAdminReset, Account: AccountBank, ActorID: actorID,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			changed++
+		}
+
+		_, err = tx.Exec(`UPDATE guild_members SET last_daily = NULL, work_streak = 0 WHERE guild_id = $1`, guildID)
+		return err
+	})
+	return changed, err
+}
+
+// nullableID stores empty IDs as NULL
+func nullableID(id string) interface{} {
+	if id == "" {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrEscrowClosed is returned when an escrow was already released or refunded
+var ErrEscrowClosed = errors.New("escrow already released or refunded")
+
+// Escrow statuses
+const (
+	EscrowHeld     = "held"
+	EscrowReleased = "released"
+	EscrowRefunded = "refunded"
+)
+
+// Escrow is a stake taken out of a member's wallet until a game or challenge
+// settles. Held escrows past their expiry are refunded by EscrowService.
+type Escrow struct {
+	ID      int64
+	GuildID string
+	UserID  string
+	Amount  int64
+	Kind    string
+	Status  string
+}
+
+// HoldEscrow takes amount from the member's wallet into a new escrow and returns its ID
+func HoldEscrow(tx *sql.Tx, guildID, userID string, amount int64, kind, reason string, ttl time.Duration) (int64, error) {
+	if _, err := LockBalance(tx, guildID, userID); err != nil {
+		return 0, err
+	}
+
+	_, err := ApplyChange(tx, BalanceChange{
+		GuildID: guildID, UserID: userID, Amount: -amount,
+		Reason: reason, ActorID: userID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var escrowID int64
+	err = tx.QueryRow(`
+		INSERT INTO escrows (guild_id, user_id, amount, kind, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING escrow_id
+	`, guildID, userID, amount, kind, time.Now().Add(ttl)).Scan(&escrowID)
+	return escrowID, err
+}
+
+// LockEscrow locks an escrow row for the rest of the transaction
+func LockEscrow(tx *sql.Tx, escrowID int64) (*Escrow, error) {
+	escrow := &Escrow{ID: escrowID}
+	err := tx.QueryRow(`
+		SELECT guild_id, user_id, amount, kind, status
+		FROM escrows
+		WHERE escrow_id = $1
+		FOR UPDATE
+	`, escrowID).Scan(&escrow.GuildID, &escrow.UserID, &escrow.Amount, &escrow.Kind, &escrow.Status)
+	return escrow, err
+}
+
+// AddToEscrow takes more from the member's wallet into a held escrow, e.g. when doubling down
+func AddToEscrow(tx *sql.Tx, escrowID, amount int64, reason string) error {
+	escrow, err := LockEscrow(tx, escrowID)
+	if err != nil {
+		return err
+	}
+	if escrow.Status != EscrowHeld {
+		return ErrEscrowClosed
+	}
+
+	if _, err := LockBalance(tx, escrow.GuildID, escrow.UserID); err != nil {
+		return err
+	}
+	_, err = ApplyChange(tx, BalanceChange{
+		GuildID: escrow.GuildID, UserID: escrow.UserID, Amount: -amount,
+		Reason: reason, ActorID: escrow.UserID,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE escrows SET amount = amount + $1 WHERE escrow_id = $2`, amount, escrowID)
+	return err
+}
+
+// ReleaseEscrow closes a held escrow so its amount can be paid out. The caller
+// credits the winners in the same transaction.
+func ReleaseEscrow(tx *sql.Tx, escrowID int64) (*Escrow, error) {
+	escrow, err := LockEscrow(tx, escrowID)
+	if err != nil {
+		return nil, err
+	}
+	if escrow.Status != EscrowHeld {
+		return nil, ErrEscrowClosed
+	}
+
+	_, err = tx.Exec(`
+		UPDATE escrows SET status = $1, closed_at = NOW() WHERE escrow_id = $2
+	`, EscrowReleased, escrowID)
+	return escrow, err
+}
+
+// RefundEscrow returns a held escrow to the member's wallet
+func RefundEscrow(tx *sql.Tx, escrowID int64) (*Escrow, error) {
+	escrow, err := LockEscrow(tx, escrowID)
+	if err != nil {
+		return nil, err
+	}
+	if escrow.Status != EscrowHeld {
+		return nil, ErrEscrowClosed
+	}
+
+	if _, err := LockBalance(tx, escrow.GuildID, escrow.UserID); err != nil {
+		return nil, err
+	}
+	_, err = ApplyChange(tx, BalanceChange{
+		GuildID: escrow.GuildID, UserID: escrow.UserID, Amount: escrow.Amount,
+		Reason: ReasonEscrowRefund,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE escrows SET status = $1, closed_at = NOW() WHERE escrow_id = $2
+	`, EscrowRefunded, escrowID)
+	return escrow, err
+}
+
+// EscrowService refunds escrows whose game or challenge was never settled,
+// including those left behind when the bot stopped before a game was recorded
+type EscrowService struct {
+	db *sql.DB
+}
+
+func NewEscrowService(db *sql.DB) *EscrowService {
+	return &EscrowService{db: db}
+}
+
+func (es *EscrowService) Start() {
+	log.Println("Starting escrow service...")
+	// Refund anything that expired while the bot was offline
+	es.refundExpired()
+
+	ticker := time.NewTicker(1 * time.Minute) // Check every minute
+	defer ticker.Stop()
+
+	for range ticker.C {
+		es.refundExpired()
+	}
+}
+
+func (es *EscrowService) refundExpired() {
+	rows, err := es.db.Query(`
+		SELECT escrow_id
+		FROM escrows
+		WHERE status = $1 AND expires_at <= NOW()
+			-- Dealt blackjack hands are settled as a stand by the casino instead
+			AND NOT (kind = 'blackjack' AND EXISTS (SELECT 1 FROM casino_games g WHERE g.escrow_id = escrows.escrow_id))
+	`, EscrowHeld)
+	if err != nil {
+		log.Printf("Error querying expired escrows: %v", err)
+		return
+	}
+
+	var escrowIDs []int64
+	for rows.Next() {
+		var escrowID int64
+		if err := rows.Scan(&escrowID); err != nil {
+			log.Printf("Error scanning escrow: %v", err)
+			continue
+		}
+		escrowIDs = append(escrowIDs, escrowID)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating over escrows: %v", err)
+	}
+	rows.Close()
+
+	for _, escrowID := range escrowIDs {
+		tx, err := es.db.Begin()
+		if err != nil {
+			log.Printf("Error starting refund for escrow %d: %v", escrowID, err)
+			continue
+		}
+
+		_, err = RefundEscrow(tx, escrowID)
+		if errors.Is(err, ErrEscrowClosed) {
+			// Settled while we were looking
+			tx.Rollback()
+			continue
+		} else if err != nil {
+			log.Printf("Error refunding escrow %d: %v", escrowID, err)
+			tx.Rollback()
+			continue
+		}
+
+		if err := tx.Commit(); err != nil {
+			log.Printf("Error committing refund for escrow %d: %v", escrowID, err)
+		}
+	}
+}
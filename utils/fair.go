@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// FairRNG is a provably-fair random source. Every number is derived from
+// HMAC-SHA256(serverSeed, "clientSeed:nonce:round"), so once the server seed is
+// revealed anyone can recompute a game's outcome. Players see the seed's hash
+// before they play, which keeps the server from changing it afterwards.
+type FairRNG struct {
+	serverSeed string
+	clientSeed string
+	nonce      int64
+	round      int
+	buf        []byte
+}
+
+// NewServerSeed returns a random hex-encoded server seed
+func NewServerSeed() (string, error) {
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(seed), nil
+}
+
+// HashSeed returns the SHA-256 commitment shown to players for a server seed
+func HashSeed(serverSeed string) string {
+	sum := sha256.Sum256([]byte(serverSeed))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewFairRNG(serverSeed, clientSeed string, nonce int64) *FairRNG {
+	return &FairRNG{
+		serverSeed: serverSeed,
+		clientSeed: clientSeed,
+		nonce:      nonce,
+	}
+}
+
+// Float returns a number in [0, 1) built from the next four bytes of the stream
+func (r *FairRNG) Float() float64 {
+	if len(r.buf) < 4 {
+		mac := hmac.New(sha256.New, []byte(r.serverSeed))
+		fmt.Fprintf(mac, "%s:%d:%d", r.clientSeed, r.nonce, r.round)
+		r.buf = mac.Sum(nil)
+		r.round++
+	}
+
+	var f, scale float64 = 0, 1
+	for _, b := range r.buf[:4] {
+		scale *= 256
+		f += float64(b) / scale
+	}
+	r.buf = r.buf[4:]
+	return f
+}
+
+// Intn returns a number in [0, n)
+func (r *FairRNG) Intn(n int) int {
+	return int(r.Float() * float64(n))
+}
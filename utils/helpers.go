@@ -103,6 +103,32 @@ func FindRole(s *discordgo.Session, guildID string, roleInput string) (*discordg
 	return nil, fmt.Errorf("role not found")
 }
 
+// RoleHolders returns the IDs of the non-bot members holding each role, paging
+// through the whole member list once
+func RoleHolders(s *discordgo.Session, guildID string) (map[string][]string, error) {
+	holders := make(map[string][]string)
+	after := ""
+	for {
+		members, err := s.GuildMembers(guildID, after, 1000)
+		if err != nil {
+			return nil, err
+		}
+		if len(members) == 0 {
+			break
+		}
+		for _, member := range members {
+			if member.User.Bot {
+				continue
+			}
+			for _, roleID := range member.Roles {
+				holders[roleID] = append(holders[roleID], member.User.ID)
+			}
+		}
+		after = members[len(members)-1].User.ID
+	}
+	return holders, nil
+}
+
 // this fetches the highest role a mod can assign, cannot be above the users/mod's current role.
 func GetHighestRole(member *discordgo.Member, roles []*discordgo.Role) *discordgo.Role {
 	var highestRole *discordgo.Role
@@ -229,4 +255,43 @@ func CheckBanMembersPermission(s *discordgo.Session, guildID, userID string) (bo
 // CheckMuteMembersPermission checks if a user has mute members permissions in a guild
 func CheckMuteMembersPermission(s *discordgo.Session, guildID, userID string) (bool, error) {
 	return CheckPermission(s, guildID, userID, discordgo.PermissionManageMessages)
-}
\ No newline at end of file
+}
+
+// InteractionUserID returns the ID of the user who triggered an interaction,
+// whether it was used in a guild or in DMs
+func InteractionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// RespondEphemeral replies to an interaction with a message only the caller can see
+func RespondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, content string) error {
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// ModalValue returns the value of a text input in a submitted modal
+func ModalValue(data discordgo.ModalSubmitInteractionData, customID string) string {
+	for _, row := range data.Components {
+		actionsRow, ok := row.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, component := range actionsRow.Components {
+			if input, ok := component.(*discordgo.TextInput); ok && input.CustomID == customID {
+				return input.Value
+			}
+		}
+	}
+	return ""
+}
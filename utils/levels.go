@@ -0,0 +1,398 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lib/pq"
+)
+
+// Level-up announcement modes
+const (
+	XPAnnounceChannel = "channel" // AnnounceChannelID, or the channel the member was chatting in
+	XPAnnounceDM      = "dm"
+	XPAnnounceOff     = "off"
+)
+
+// XPConfig holds a guild's chat XP settings. Reaching level n takes
+// CurveBase * n^CurveExponent XP in total.
+type XPConfig struct {
+	Enabled           bool
+	MinXP             int64
+	MaxXP             int64
+	Cooldown          time.Duration // between messages that earn XP
+	CurveBase         float64
+	CurveExponent     float64
+	Announce          string
+	AnnounceChannelID string // empty means the channel the member was chatting in
+	ExcludedChannels  []string
+	StackRoles        bool // keep lower level roles when a higher one is reached
+}
+
+// DefaultXPConfig matches the column defaults
+func DefaultXPConfig() *XPConfig {
+	return &XPConfig{
+		MinXP:         15,
+		MaxXP:         25,
+		Cooldown:      time.Minute,
+		CurveBase:     100,
+		CurveExponent: 1.5,
+		Announce:      XPAnnounceChannel,
+		StackRoles:    true,
+	}
+}
+
+// LoadXPConfig returns the guild's XP settings, falling back to the defaults
+func LoadXPConfig(q Querier, guildID string) (*XPConfig, error) {
+	config := DefaultXPConfig()
+	cooldownSeconds := int(config.Cooldown.Seconds())
+	err := q.QueryRow(`
+		SELECT COALESCE(xp_enabled, FALSE), COALESCE(xp_min, $2), COALESCE(xp_max, $3),
+			COALESCE(xp_cooldown_seconds, $4), COALESCE(xp_curve_base, $5), COALESCE(xp_curve_exponent, $6),
+			COALESCE(xp_announce, $7), COALESCE(xp_announce_channel_id::text, ''),
+			COALESCE(xp_excluded_channels, '{}'), COALESCE(xp_stack_roles, TRUE)
+		FROM guilds
+		WHERE guild_id = $1
+	`, guildID, config.MinXP, config.MaxXP, cooldownSeconds, config.CurveBase, config.CurveExponent, config.Announce).Scan(
+		&config.Enabled, &config.MinXP, &config.MaxXP, &cooldownSeconds, &config.CurveBase, &config.CurveExponent,
+		&config.Announce, &config.AnnounceChannelID, pq.Array(&config.ExcludedChannels), &config.StackRoles)
+	if err == sql.ErrNoRows {
+		return config, nil
+	}
+	config.Cooldown = time.Duration(cooldownSeconds) * time.Second
+	return config, err
+}
+
+// XPForLevel returns the total XP needed to reach a level
+func (xc *XPConfig) XPForLevel(level int) int64 {
+	if level <= 0 {
+		return 0
+	}
+	return int64(math.Round(xc.CurveBase * math.Pow(float64(level), xc.CurveExponent)))
+}
+
+// LevelForXP returns the level reached with a total amount of XP
+func (xc *XPConfig) LevelForXP(xp int64) int {
+	level := int(math.Pow(float64(xp)/xc.CurveBase, 1/xc.CurveExponent))
+	// Correct for floating point error at the level boundaries
+	for level > 0 && xc.XPForLevel(level) > xp {
+		level--
+	}
+	for xc.XPForLevel(level+1) <= xp {
+		level++
+	}
+	return level
+}
+
+// IsExcluded reports whether messages in the channel earn no XP
+func (xc *XPConfig) IsExcluded(channelID string) bool {
+	for _, excluded := range xc.ExcludedChannels {
+		if excluded == channelID {
+			return true
+		}
+	}
+	return false
+}
+
+// XPMultiplier returns the best XP multiplier among the given roles, or 1
+func XPMultiplier(q Querier, guildID string, roleIDs []string) (float64, error) {
+	if len(roleIDs) == 0 {
+		return 1, nil
+	}
+	var multiplier sql.NullFloat64
+	err := q.QueryRow(`
+		SELECT MAX(multiplier) FROM role_xp_multipliers WHERE guild_id = $1 AND role_id::text = ANY($2)
+	`, guildID, pq.Array(roleIDs)).Scan(&multiplier)
+	if err != nil || !multiplier.Valid {
+		return 1, err
+	}
+	return multiplier.Float64, nil
+}
+
+// Expired cooldowns are pruned once this many members are tracked
+const maxTrackedCooldowns = 10000
+
+// LevelService awards chat XP, announces level-ups and keeps level roles in sync
+type LevelService struct {
+	db      *sql.DB
+	session *discordgo.Session
+
+	mu sync.Mutex
+	// nextAward skips the database for members still on cooldown, keyed by guildID:userID.
+	// The database enforces the cooldown either way.
+	nextAward map[string]time.Time
+}
+
+func NewLevelService(db *sql.DB, session *discordgo.Session) *LevelService {
+	return &LevelService{db: db, session: session, nextAward: make(map[string]time.Time)}
+}
+
+// HandleMessage awards XP for a chat message if the member is off cooldown
+func (ls *LevelService) HandleMessage(m *discordgo.MessageCreate) {
+	if m.GuildID == "" || m.Author.Bot || m.Member == nil {
+		return
+	}
+
+	key := m.GuildID + ":" + m.Author.ID
+	now := time.Now()
+	ls.mu.Lock()
+	if now.Before(ls.nextAward[key]) {
+		ls.mu.Unlock()
+		return
+	}
+	ls.mu.Unlock()
+
+	config, err := LoadXPConfig(ls.db, m.GuildID)
+	if err != nil {
+		log.Printf("Error loading XP config for guild %s: %v", m.GuildID, err)
+		return
+	}
+	if !config.Enabled || config.IsExcluded(m.ChannelID) {
+		return
+	}
+	// Threads follow their parent channel
+	if channel, err := ls.session.State.Channel(m.ChannelID); err == nil && channel.IsThread() && config.IsExcluded(channel.ParentID) {
+		return
+	}
+
+	ls.mu.Lock()
+	if len(ls.nextAward) > maxTrackedCooldowns {
+		for k, next := range ls.nextAward {
+			if now.After(next) {
+				delete(ls.nextAward, k)
+			}
+		}
+	}
+	ls.nextAward[key] = now.Add(config.Cooldown)
+	ls.mu.Unlock()
+
+	multiplier, err := XPMultiplier(ls.db, m.GuildID, m.Member.Roles)
+	if err != nil {
+		log.Printf("Error querying XP multipliers: %v", err)
+	}
+	amount := config.MinXP
+	if config.MaxXP > config.MinXP {
+		amount += rand.Int63n(config.MaxXP - config.MinXP + 1)
+	}
+	amount = int64(math.Round(float64(amount) * multiplier))
+
+	var xp int64
+	var level int
+	err = ls.db.QueryRow(`
+		INSERT INTO member_levels (guild_id, user_id, xp, messages, last_message_xp_at)
+		VALUES ($1, $2, $3, 1, NOW())
+		ON CONFLICT (guild_id, user_id) DO UPDATE SET
+			xp = member_levels.xp + EXCLUDED.xp,
+			messages = member_levels.messages + 1,
+			last_message_xp_at = NOW()
+		WHERE member_levels.last_message_xp_at IS NULL
+			OR member_levels.last_message_xp_at <= NOW() - make_interval(secs => $4)
+		RETURNING xp, level
+	`, m.GuildID, m.Author.ID, amount, config.Cooldown.Seconds()).Scan(&xp, &level)
+	if err == sql.ErrNoRows {
+		// Still on cooldown, e.g. after a restart
+		return
+	} else if err != nil {
+		log.Printf("Error awarding XP to %s in guild %s: %v", m.Author.ID, m.GuildID, err)
+		return
+	}
+
+	ls.checkLevelUp(config, m.GuildID, m.Author.ID, m.ChannelID, m.Member.Roles, xp, level)
+}
+
+// AddXP awards a fixed amount of XP outside chat, e.g. for time in voice. Role
+// multipliers apply but the message cooldown doesn't.
+func (ls *LevelService) AddXP(guildID, userID, channelID string, amount int64) {
+	config, err := LoadXPConfig(ls.db, guildID)
+	if err != nil {
+		log.Printf("Error loading XP config for guild %s: %v", guildID, err)
+		return
+	}
+	if !config.Enabled || config.IsExcluded(channelID) {
+		return
+	}
+
+	member, err := ls.session.State.Member(guildID, userID)
+	if err != nil {
+		member, err = ls.session.GuildMember(guildID, userID)
+		if err != nil {
+			log.Printf("Error fetching member %s in guild %s: %v", userID, guildID, err)
+			return
+		}
+	}
+
+	multiplier, err := XPMultiplier(ls.db, guildID, member.Roles)
+	if err != nil {
+		log.Printf("Error querying XP multipliers: %v", err)
+	}
+	amount = int64(math.Round(float64(amount) * multiplier))
+
+	var xp int64
+	var level int
+	err = ls.db.QueryRow(`
+		INSERT INTO member_levels (guild_id, user_id, xp)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (guild_id, user_id) DO UPDATE SET xp = member_levels.xp + EXCLUDED.xp
+		RETURNING xp, level
+	`, guildID, userID, amount).Scan(&xp, &level)
+	if err != nil {
+		log.Printf("Error awarding XP to %s in guild %s: %v", userID, guildID, err)
+		return
+	}
+
+	ls.checkLevelUp(config, guildID, userID, channelID, member.Roles, xp, level)
+}
+
+// checkLevelUp records a new level reached with xp, syncs the member's level roles and
+// announces it. Only the caller that moves the stored level announces it.
+func (ls *LevelService) checkLevelUp(config *XPConfig, guildID, userID, channelID string, memberRoles []string, xp int64, storedLevel int) {
+	level := config.LevelForXP(xp)
+	if level <= storedLevel {
+		return
+	}
+
+	result, err := ls.db.Exec(`
+		UPDATE member_levels SET level = $3 WHERE guild_id = $1 AND user_id = $2 AND level < $3
+	`, guildID, userID, level)
+	if err != nil {
+		log.Printf("Error updating level for %s in guild %s: %v", userID, guildID, err)
+		return
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return
+	}
+
+	added, err := ls.SyncLevelRoles(config, guildID, userID, memberRoles, level)
+	if err != nil {
+		log.Printf("Error syncing level roles for %s in guild %s: %v", userID, guildID, err)
+	}
+	ls.announceLevelUp(config, guildID, userID, channelID, level, added)
+}
+
+// SyncLevelRoles gives the member the level roles they have reached and, unless roles
+// stack, removes the lower ones. It returns the roles that were added.
+func (ls *LevelService) SyncLevelRoles(config *XPConfig, guildID, userID string, memberRoles []string, level int) ([]string, error) {
+	rows, err := ls.db.Query(`
+		SELECT level, role_id FROM level_roles WHERE guild_id = $1 ORDER BY level
+	`, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reached, unreached []string
+	for rows.Next() {
+		var roleLevel int
+		var roleID string
+		if err := rows.Scan(&roleLevel, &roleID); err != nil {
+			return nil, err
+		}
+		if roleLevel <= level {
+			reached = append(reached, roleID)
+		} else {
+			unreached = append(unreached, roleID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	keep := reached
+	remove := unreached
+	if !config.StackRoles && len(reached) > 1 {
+		keep = reached[len(reached)-1:]
+		remove = append(remove, reached[:len(reached)-1]...)
+	}
+
+	has := make(map[string]bool, len(memberRoles))
+	for _, roleID := range memberRoles {
+		has[roleID] = true
+	}
+
+	var added []string
+	for _, roleID := range keep {
+		if has[roleID] {
+			continue
+		}
+		if err := ls.session.GuildMemberRoleAdd(guildID, userID, roleID); err != nil {
+			log.Printf("Error adding level role %s to %s: %v", roleID, userID, err)
+			continue
+		}
+		added = append(added, roleID)
+	}
+	for _, roleID := range remove {
+		if !has[roleID] {
+			continue
+		}
+		if err := ls.session.GuildMemberRoleRemove(guildID, userID, roleID); err != nil {
+			log.Printf("Error removing level role %s from %s: %v", roleID, userID, err)
+		}
+	}
+	return added, nil
+}
+
+func (ls *LevelService) announceLevelUp(config *XPConfig, guildID, userID, channelID string, level int, roles []string) {
+	message := fmt.Sprintf("🎉 <@%s> reached level %d!", userID, level)
+	for _, roleID := range roles {
+		message += fmt.Sprintf(" You earned <@&%s>.", roleID)
+	}
+
+	switch config.Announce {
+	case XPAnnounceOff:
+		return
+	case XPAnnounceDM:
+		channel, err := ls.session.UserChannelCreate(userID)
+		if err != nil {
+			log.Printf("Error opening DM with %s: %v", userID, err)
+			return
+		}
+		channelID = channel.ID
+		if guild, err := ls.session.State.Guild(guildID); err == nil {
+			message = fmt.Sprintf("🎉 You reached level %d in %s!", level, guild.Name)
+		}
+	default:
+		if config.AnnounceChannelID != "" {
+			channelID = config.AnnounceChannelID
+		}
+	}
+
+	_, err := ls.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: message,
+		// Ping the member, never the roles
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{userID}},
+	})
+	if err != nil {
+		log.Printf("Error announcing level up for %s in guild %s: %v", userID, guildID, err)
+	}
+}
+
+// MemberLevel is a member's XP standing in a guild
+type MemberLevel struct {
+	XP       int64
+	Level    int
+	Messages int64
+	Rank     int
+}
+
+// GetMemberLevel returns the member's XP and their position in the guild
+func GetMemberLevel(q Querier, guildID, userID string) (*MemberLevel, error) {
+	member := &MemberLevel{}
+	err := q.QueryRow(`
+		SELECT COALESCE((SELECT xp FROM member_levels WHERE guild_id = $1 AND user_id = $2), 0),
+			COALESCE((SELECT messages FROM member_levels WHERE guild_id = $1 AND user_id = $2), 0)
+	`, guildID, userID).Scan(&member.XP, &member.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.QueryRow(`
+		SELECT COUNT(*) + 1 FROM member_levels WHERE guild_id = $1 AND xp > $2
+	`, guildID, member.XP).Scan(&member.Rank)
+	return member, err
+}
@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// LotteryConfig holds a guild's lottery settings. Draws happen weekly at
+// DrawWeekday and DrawMinute (minutes after midnight), both in UTC.
+type LotteryConfig struct {
+	Enabled     bool
+	TicketPrice int64
+	DrawWeekday time.Weekday
+	DrawMinute  int
+	ChannelID   string // empty when draws aren't announced
+}
+
+// Lottery is a round of a guild's lottery
+type Lottery struct {
+	ID         int64
+	GuildID    string
+	ServerSeed string // only its hash is shown until the draw
+	Pot        int64
+	Tickets    int64
+	DrawAt     time.Time
+}
+
+// LoadLotteryConfig returns the guild's lottery settings, matching the column defaults
+func LoadLotteryConfig(q Querier, guildID string) (*LotteryConfig, error) {
+	config := &LotteryConfig{TicketPrice: 100, DrawWeekday: time.Sunday, DrawMinute: 20 * 60}
+	var weekday int
+	err := q.QueryRow(`
+		SELECT COALESCE(lottery_enabled, FALSE), COALESCE(lottery_ticket_price, $2),
+			COALESCE(lottery_weekday, $3), COALESCE(lottery_minute, $4), COALESCE(lottery_channel_id::text, '')
+		FROM guilds
+		WHERE guild_id = $1
+	`, guildID, config.TicketPrice, int(config.DrawWeekday), config.DrawMinute).Scan(
+		&config.Enabled, &config.TicketPrice, &weekday, &config.DrawMinute, &config.ChannelID)
+	if err == sql.ErrNoRows {
+		return config, nil
+	}
+	config.DrawWeekday = time.Weekday(weekday)
+	return config, err
+}
+
+// NextDraw returns the first scheduled draw after now
+func (lc *LotteryConfig) NextDraw(now time.Time) time.Time {
+	now = now.UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	days := (int(lc.DrawWeekday) - int(now.Weekday()) + 7) % 7
+	draw := midnight.AddDate(0, 0, days).Add(time.Duration(lc.DrawMinute) * time.Minute)
+	if !draw.After(now) {
+		draw = draw.AddDate(0, 0, 7)
+	}
+	return draw
+}
+
+// OpenLottery starts a new round for the guild unless one is already open
+func OpenLottery(q Querier, guildID string, drawAt time.Time) error {
+	serverSeed, err := NewServerSeed()
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(`
+		INSERT INTO lotteries (guild_id, server_seed, draw_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (guild_id) WHERE status = 'open' DO NOTHING
+	`, guildID, serverSeed, drawAt)
+	return err
+}
+
+// CurrentLottery returns the guild's open round, or sql.ErrNoRows if there is none.
+// With a transaction the round stays locked until it commits.
+func CurrentLottery(q Querier, guildID string, lock bool) (*Lottery, error) {
+	query := `
+		SELECT lottery_id, server_seed, pot, tickets, draw_at
+		FROM lotteries
+		WHERE guild_id = $1 AND status = 'open'`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+
+	lottery := &Lottery{GuildID: guildID}
+	err := q.QueryRow(query, guildID).Scan(&lottery.ID, &lottery.ServerSeed, &lottery.Pot, &lottery.Tickets, &lottery.DrawAt)
+	return lottery, err
+}
+
+// LotteryService draws each guild's lottery when it is due and opens the next round.
+// Rounds and their draw times live in the database, so draws missed while the
+// bot was offline happen as soon as it starts again.
+type LotteryService struct {
+	db      *sql.DB
+	session *discordgo.Session
+}
+
+func NewLotteryService(db *sql.DB, session *discordgo.Session) *LotteryService {
+	return &LotteryService{db: db, session: session}
+}
+
+func (ls *LotteryService) Start() {
+	log.Println("Starting lottery service...")
+	ls.drawDue()
+
+	ticker := time.NewTicker(1 * time.Minute) // Check every minute
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ls.drawDue()
+	}
+}
+
+func (ls *LotteryService) drawDue() {
+	rows, err := ls.db.Query(`
+		SELECT guild_id
+		FROM lotteries
+		WHERE status = 'open' AND draw_at <= NOW()
+	`)
+	if err != nil {
+		log.Printf("Error querying due lotteries: %v", err)
+		return
+	}
+
+	var guildIDs []string
+	for rows.Next() {
+		var guildID string
+		if err := rows.Scan(&guildID); err != nil {
+			log.Printf("Error scanning lottery: %v", err)
+			continue
+		}
+		guildIDs = append(guildIDs, guildID)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating over lotteries: %v", err)
+	}
+	rows.Close()
+
+	for _, guildID := range guildIDs {
+		if err := ls.draw(guildID); err != nil {
+			log.Printf("Error drawing lottery for guild %s: %v", guildID, err)
+		}
+	}
+}
+
+// draw picks the winning ticket, pays the pot and opens the next round in one
+// transaction, then announces the result
+func (ls *LotteryService) draw(guildID string) error {
+	tx, err := ls.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	lottery, err := CurrentLottery(tx, guildID, true)
+	if err == sql.ErrNoRows || (err == nil && lottery.DrawAt.After(time.Now())) {
+		// Drawn or rescheduled while we were looking
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var winnerID string
+	var winningTicket int64
+	if lottery.Tickets > 0 {
+		// Ticket numbers run from 0 in the order they were bought
+		rng := NewFairRNG(lottery.ServerSeed, guildID, lottery.ID)
+		winningTicket = int64(rng.Intn(int(lottery.Tickets)))
+
+		err = tx.QueryRow(`
+			SELECT user_id
+			FROM lottery_tickets
+			WHERE lottery_id = $1 AND first_ticket <= $2
+			ORDER BY first_ticket DESC
+			LIMIT 1
+		`, lottery.ID, winningTicket).Scan(&winnerID)
+		if err != nil {
+			return fmt.Errorf("error finding winning ticket: %w", err)
+		}
+
+		if _, err := LockBalance(tx, guildID, winnerID); err != nil {
+			return err
+		}
+		_, err = ApplyChange(tx, BalanceChange{
+			GuildID: guildID, UserID: winnerID, Amount: lottery.Pot,
+			Reason: ReasonLotteryPrize,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE lotteries
+		SET status = 'drawn', winner_id = $1, winning_ticket = $2, drawn_at = NOW()
+		WHERE lottery_id = $3
+	`, nullableID(winnerID), winningTicket, lottery.ID)
+	if err != nil {
+		return err
+	}
+
+	config, err := LoadLotteryConfig(tx, guildID)
+	if err != nil {
+		return err
+	}
+	if config.Enabled {
+		if err := OpenLottery(tx, guildID, config.NextDraw(time.Now())); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	ls.announce(lottery, config, winnerID, winningTicket)
+	return nil
+}
+
+func (ls *LotteryService) announce(lottery *Lottery, config *LotteryConfig, winnerID string, winningTicket int64) {
+	if config.ChannelID == "" {
+		return
+	}
+
+	settings := DefaultEconomySettings()
+	if guildSettings, err := NewEconomyService(ls.db).Settings(lottery.GuildID); err == nil {
+		settings = guildSettings
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "🎟️ Lottery Draw",
+		Description: "No tickets were sold this round.",
+		Color:       0x808080,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Round #%d • Server seed %s • Client seed %s • Nonce %d",
+				lottery.ID, lottery.ServerSeed, lottery.GuildID, lottery.ID),
+		},
+	}
+	if winnerID != "" {
+		embed.Description = fmt.Sprintf("🎉 <@%s> wins %s with ticket #%d of %d!",
+			winnerID, settings.Format(lottery.Pot), winningTicket+1, lottery.Tickets)
+		embed.Color = 0xffd700
+	}
+	if config.Enabled {
+		embed.Description += fmt.Sprintf("\nThe next draw is <t:%d:F>. Buy tickets with `.lottery buy [amount]`.", config.NextDraw(time.Now()).Unix())
+	}
+
+	if _, err := ls.session.ChannelMessageSendEmbed(config.ChannelID, embed); err != nil {
+		log.Printf("Error announcing lottery for guild %s: %v", lottery.GuildID, err)
+	}
+}
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"database/sql"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Role income periods
+const (
+	IncomeHourly = "hourly"
+	IncomeDaily  = "daily"
+)
+
+// IncomePeriodStart returns the start of the pay period containing now, in UTC
+func IncomePeriodStart(period string, now time.Time) time.Time {
+	now = now.UTC()
+	if period == IncomeDaily {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return now.Truncate(time.Hour)
+}
+
+// RoleIncomeService pays passive income to members holding configured roles.
+// Each role records the last period it was paid for in the same transaction as
+// the payouts, so a period is never paid twice, even across restarts. Periods
+// that pass while the bot is offline are skipped, as there is no record of who
+// held the role at the time.
+type RoleIncomeService struct {
+	db      *sql.DB
+	session *discordgo.Session
+}
+
+func NewRoleIncomeService(db *sql.DB, session *discordgo.Session) *RoleIncomeService {
+	return &RoleIncomeService{db: db, session: session}
+}
+
+func (rs *RoleIncomeService) Start() {
+	log.Println("Starting role income service...")
+	rs.payDueIncome()
+
+	ticker := time.NewTicker(1 * time.Minute) // Check every minute
+	defer ticker.Stop()
+
+	for range ticker.C {
+		rs.payDueIncome()
+	}
+}
+
+// dueRoleIncome is a role whose current period hasn't been paid yet
+type dueRoleIncome struct {
+	roleID string
+	period string
+}
+
+func (rs *RoleIncomeService) payDueIncome() {
+	now := time.Now()
+	rows, err := rs.db.Query(`
+		SELECT guild_id, role_id, period
+		FROM role_income
+		WHERE paid_period IS NULL
+			OR (period = $1 AND paid_period < $2)
+			OR (period = $3 AND paid_period < $4)
+	`, IncomeHourly, IncomePeriodStart(IncomeHourly, now), IncomeDaily, IncomePeriodStart(IncomeDaily, now))
+	if err != nil {
+		log.Printf("Error querying due role income: %v", err)
+		return
+	}
+
+	due := make(map[string][]dueRoleIncome)
+	for rows.Next() {
+		var guildID string
+		var income dueRoleIncome
+		if err := rows.Scan(&guildID, &income.roleID, &income.period); err != nil {
+			log.Printf("Error scanning role income: %v", err)
+			continue
+		}
+		due[guildID] = append(due[guildID], income)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating over role income: %v", err)
+	}
+	rows.Close()
+
+	for guildID, incomes := range due {
+		// One member list fetch covers every role in the guild
+		holders, err := RoleHolders(rs.session, guildID)
+		if err != nil {
+			log.Printf("Error fetching members for role income in guild %s: %v", guildID, err)
+			continue
+		}
+
+		for _, income := range incomes {
+			paid, err := rs.payRole(guildID, income, holders[income.roleID], now)
+			if err != nil {
+				log.Printf("Error paying income for role %s in guild %s: %v", income.roleID, guildID, err)
+				continue
+			}
+			if paid > 0 {
+				log.Printf("Paid role income to %d members of role %s in guild %s", paid, income.roleID, guildID)
+			}
+		}
+	}
+}
+
+// payRole claims the current period for a role and pays every holder in one transaction
+func (rs *RoleIncomeService) payRole(guildID string, income dueRoleIncome, userIDs []string, now time.Time) (int, error) {
+	periodStart := IncomePeriodStart(income.period, now)
+
+	tx, err := rs.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var amount int64
+	err = tx.QueryRow(`
+		UPDATE role_income
+		SET paid_period = $1
+		WHERE guild_id = $2 AND role_id = $3 AND (paid_period IS NULL OR paid_period < $1)
+		RETURNING amount
+	`, periodStart, guildID, income.roleID).Scan(&amount)
+	if err == sql.ErrNoRows {
+		// Already paid, or the income was removed
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	// Lock rows in a fixed order so payouts can't deadlock with grants
+	ids := append([]string(nil), userIDs...)
+	sort.Strings(ids)
+	for _, userID := range ids {
+		if _, err := LockBalance(tx, guildID, userID); err != nil {
+			return 0, err
+		}
+		_, err = ApplyChange(tx, BalanceChange{
+			GuildID: guildID, UserID: userID, Amount: amount,
+			Reason: ReasonRoleIncome,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(userIDs), tx.Commit()
+}
@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Season reset modes
+const (
+	SeasonReset = "reset" // back to the starting balance with an empty bank
+	SeasonScale = "scale" // keep ScalePercent of the wallet and bank
+)
+
+var (
+	// ErrEmptySeason is returned when a season is closed in a guild with no members
+	ErrEmptySeason = errors.New("no members to rank")
+	// ErrSeasonEnded is returned when the season to close has already ended
+	ErrSeasonEnded = errors.New("season already ended")
+)
+
+// SeasonConfig holds a guild's current season and what happens when it ends
+type SeasonConfig struct {
+	Number       int
+	StartedAt    time.Time
+	TopN         int    // members awarded RoleID
+	RoleID       string // empty when no role is awarded
+	Mode         string
+	ScalePercent int
+}
+
+// Season is an archived season
+type Season struct {
+	ID           int64
+	Number       int
+	StartedAt    time.Time
+	EndedAt      time.Time
+	Mode         string
+	ScalePercent int
+	Members      int
+	TopN         int // the season settings when it ended, not archived
+	RoleID       string
+}
+
+// SeasonStanding is a member's final position in a season. Members are ranked
+// by wallet balance, like the leaderboard.
+type SeasonStanding struct {
+	Rank    int
+	UserID  string
+	Balance int64
+	Bank    int64
+}
+
+// LoadSeasonConfig returns the guild's season settings, matching the column defaults
+func LoadSeasonConfig(q Querier, guildID string) (*SeasonConfig, error) {
+	config := &SeasonConfig{Number: 1, StartedAt: time.Now(), TopN: 3, Mode: SeasonReset, ScalePercent: 10}
+	err := q.QueryRow(`
+		SELECT COALESCE(season_number, $2), COALESCE(season_started_at, created_at, NOW()),
+			COALESCE(season_top_n, $3), COALESCE(season_role_id::text, ''),
+			COALESCE(season_reset_mode, $4), COALESCE(season_scale_percent, $5)
+		FROM guilds
+		WHERE guild_id = $1
+	`, guildID, config.Number, config.TopN, config.Mode, config.ScalePercent).Scan(
+		&config.Number, &config.StartedAt, &config.TopN, &config.RoleID, &config.Mode, &config.ScalePercent)
+	if err == sql.ErrNoRows {
+		return config, nil
+	}
+	return config, err
+}
+
+// EndSeason archives the guild's leaderboard, resets or scales every balance
+// according to the season settings and starts the next season, all in one
+// transaction. number is the season the caller means to close, so a repeated
+// request can't close the next one. It returns the archived season and its
+// standings, best first.
+func (es *EconomyService) EndSeason(guildID, actorID string, number int) (*Season, []SeasonStanding, error) {
+	var season *Season
+	var standings []SeasonStanding
+	err := es.WithTx(func(tx *sql.Tx) error {
+		// Locking the guild row keeps two admins from closing the same season
+		var startingBalance int64
+		err := tx.QueryRow(`
+			SELECT COALESCE(starting_balance, 0) FROM guilds WHERE guild_id = $1 FOR UPDATE
+		`, guildID).Scan(&startingBalance)
+		if err == sql.ErrNoRows {
+			return ErrEmptySeason
+		} else if err != nil {
+			return err
+		}
+
+		config, err := LoadSeasonConfig(tx, guildID)
+		if err != nil {
+			return err
+		}
+		if config.Number != number {
+			return ErrSeasonEnded
+		}
+
+		rows, err := tx.Query(`
+			SELECT user_id, balance, bank_balance FROM guild_members
+			WHERE guild_id = $1
+			ORDER BY user_id
+			FOR UPDATE
+		`, guildID)
+		if err != nil {
+			return err
+		}
+		var members []MemberBalances
+		for rows.Next() {
+			var member MemberBalances
+			if err := rows.Scan(&member.UserID, &member.Balance, &member.Bank); err != nil {
+				rows.Close()
+				return err
+			}
+			members = append(members, member)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			return ErrEmptySeason
+		}
+
+		// Rank in leaderboard order, ties broken by user ID so the archive is stable
+		ranked := append([]MemberBalances(nil), members...)
+		sort.Slice(ranked, func(i, j int) bool {
+			if ranked[i].Balance != ranked[j].Balance {
+				return ranked[i].Balance > ranked[j].Balance
+			}
+			return ranked[i].UserID < ranked[j].UserID
+		})
+
+		season = &Season{
+			Number: config.Number, StartedAt: config.StartedAt,
+			Mode: config.Mode, ScalePercent: config.ScalePercent, Members: len(ranked),
+			TopN: config.TopN, RoleID: config.RoleID,
+		}
+		err = tx.QueryRow(`
+			INSERT INTO seasons (guild_id, number, started_at, ended_by, reset_mode, scale_percent, members)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING season_id, ended_at
+		`, guildID, season.Number, season.StartedAt, actorID, season.Mode, season.ScalePercent, season.Members).Scan(&season.ID, &season.EndedAt)
+		if err != nil {
+			return err
+		}
+
+		ranks := make([]int64, len(ranked))
+		userIDs := make([]string, len(ranked))
+		balances := make([]int64, len(ranked))
+		banks := make([]int64, len(ranked))
+		standings = make([]SeasonStanding, len(ranked))
+		for i, member := range ranked {
+			standings[i] = SeasonStanding{Rank: i + 1, UserID: member.UserID, Balance: member.Balance, Bank: member.Bank}
+			ranks[i], userIDs[i], balances[i], banks[i] = int64(i+1), member.UserID, member.Balance, member.Bank
+		}
+		_, err = tx.Exec(`
+			INSERT INTO season_standings (season_id, rank, user_id, balance, bank_balance)
+			SELECT $1, * FROM unnest($2::int[], $3::bigint[], $4::bigint[], $5::bigint[])
+		`, season.ID, pq.Array(ranks), pq.Array(userIDs), pq.Array(balances), pq.Array(banks))
+		if err != nil {
+			return err
+		}
+
+		for i := range members {
+			if config.Mode == SeasonScale {
+				members[i].Balance = members[i].Balance * int64(config.ScalePercent) / 100
+				members[i].Bank = members[i].Bank * int64(config.ScalePercent) / 100
+			} else {
+				members[i].Balance, members[i].Bank = startingBalance, 0
+			}
+		}
+		if _, err := SetBalances(tx, guildID, actorID, ReasonSeasonReset, members); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE guilds SET season_number = $1, season_started_at = NOW() WHERE guild_id = $2
+		`, config.Number+1, guildID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return season, standings, nil
+}
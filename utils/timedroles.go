@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Sources recorded for timed roles
+const (
+	RoleSourceSetRole = "setrole"
+	RoleSourceShop    = "shop"
+	RoleSourceMute    = "mute"
+)
+
+// Querier is satisfied by both *sql.DB and *sql.Tx
+type Querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// ExtendRoleExpiry makes a member's role expire after duration. If the role is
+// already timed, the duration is added to the remaining time. It returns the new expiry.
+func ExtendRoleExpiry(q Querier, guildID, userID, roleID string, duration time.Duration, source string) (time.Time, error) {
+	var expiresAt time.Time
+	err := q.QueryRow(`
+		INSERT INTO timed_roles (guild_id, user_id, role_id, expires_at, source)
+		VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 second', $5)
+		ON CONFLICT (guild_id, user_id, role_id) DO UPDATE SET
+			expires_at = GREATEST(timed_roles.expires_at, NOW()) + $4 * INTERVAL '1 second',
+			source = EXCLUDED.source
+		RETURNING expires_at
+	`, guildID, userID, roleID, int64(duration.Seconds()), source).Scan(&expiresAt)
+	return expiresAt, err
+}
+
+// ClearRoleExpiry makes a member's role permanent again
+func ClearRoleExpiry(q Querier, guildID, userID, roleID string) error {
+	_, err := q.Exec(`
+		DELETE FROM timed_roles
+		WHERE guild_id = $1 AND user_id = $2 AND role_id = $3
+	`, guildID, userID, roleID)
+	return err
+}
+
+// TimedRoleService removes roles when their expiry passes and lets the member know
+type TimedRoleService struct {
+	db      *sql.DB
+	session *discordgo.Session
+}
+
+func NewTimedRoleService(db *sql.DB, session *discordgo.Session) *TimedRoleService {
+	return &TimedRoleService{
+		db:      db,
+		session: session,
+	}
+}
+
+func (ts *TimedRoleService) Start() {
+	log.Println("Starting timed role service...")
+	ticker := time.NewTicker(1 * time.Minute) // Check every minute
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ts.removeExpiredRoles()
+	}
+}
+
+type expiredRole struct {
+	guildID   string
+	userID    string
+	roleID    string
+	expiresAt time.Time
+	source    string
+}
+
+func (ts *TimedRoleService) removeExpiredRoles() {
+	// Claim the expired rows first, so an expiry extended in the meantime keeps
+	// its row and its role
+	rows, err := ts.db.Query(`
+		DELETE FROM timed_roles
+		WHERE expires_at <= NOW()
+		RETURNING guild_id, user_id, role_id, expires_at, source
+	`)
+	if err != nil {
+		log.Printf("Error claiming expired timed roles: %v", err)
+		return
+	}
+
+	// Collect first so the connection isn't held while talking to Discord
+	var expired []expiredRole
+	for rows.Next() {
+		var role expiredRole
+		if err := rows.Scan(&role.guildID, &role.userID, &role.roleID, &role.expiresAt, &role.source); err != nil {
+			log.Printf("Error scanning timed role: %v", err)
+			continue
+		}
+		expired = append(expired, role)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating over timed roles: %v", err)
+	}
+	rows.Close()
+
+	for _, role := range expired {
+		removeErr := ts.session.GuildMemberRoleRemove(role.guildID, role.userID, role.roleID)
+		if removeErr != nil {
+			// The member or role is gone, nobody to tell
+			if !isNotFound(removeErr) {
+				log.Printf("Error removing expired role %s from user %s: %v", role.roleID, role.userID, removeErr)
+				ts.restoreTimedRole(role)
+			}
+			continue
+		}
+
+		// The role may have been bought again after the row was claimed
+		renewed, err := ts.isTimed(role)
+		if err != nil {
+			log.Printf("Error checking timed role %s for user %s: %v", role.roleID, role.userID, err)
+		}
+		if renewed {
+			if err := ts.session.GuildMemberRoleAdd(role.guildID, role.userID, role.roleID); err != nil {
+				log.Printf("Error restoring renewed role %s to user %s: %v", role.roleID, role.userID, err)
+			}
+			continue
+		}
+
+		ts.notifyExpired(role)
+	}
+}
+
+// restoreTimedRole puts a claimed row back so the removal is retried, unless the
+// role was timed again in the meantime
+func (ts *TimedRoleService) restoreTimedRole(role expiredRole) {
+	_, err := ts.db.Exec(`
+		INSERT INTO timed_roles (guild_id, user_id, role_id, expires_at, source)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (guild_id, user_id, role_id) DO NOTHING
+	`, role.guildID, role.userID, role.roleID, role.expiresAt, role.source)
+	if err != nil {
+		log.Printf("Error restoring timed role %s for user %s: %v", role.roleID, role.userID, err)
+	}
+}
+
+// isTimed reports whether the member's role has a timed_roles row again
+func (ts *TimedRoleService) isTimed(role expiredRole) (bool, error) {
+	var exists bool
+	err := ts.db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM timed_roles WHERE guild_id = $1 AND user_id = $2 AND role_id = $3)
+	`, role.guildID, role.userID, role.roleID).Scan(&exists)
+	return exists, err
+}
+
+func (ts *TimedRoleService) notifyExpired(role expiredRole) {
+	roleName := role.roleID
+	if r, err := ts.session.State.Role(role.guildID, role.roleID); err == nil {
+		roleName = r.Name
+	}
+	guildName := role.guildID
+	if g, err := ts.session.State.Guild(role.guildID); err == nil {
+		guildName = g.Name
+	}
+
+	channel, err := ts.session.UserChannelCreate(role.userID)
+	if err != nil {
+		log.Printf("Error creating DM channel for user %s: %v", role.userID, err)
+		return
+	}
+
+	_, err = ts.session.ChannelMessageSend(channel.ID,
+		fmt.Sprintf(":hourglass: Your role **%s** in **%s** has expired.", roleName, guildName))
+	if err != nil {
+		log.Printf("Error sending role expiry DM to user %s: %v", role.userID, err)
+	}
+}
+
+// isNotFound reports whether Discord answered 404, e.g. for an unknown member or role
+func isNotFound(err error) bool {
+	restErr, ok := err.(*discordgo.RESTError)
+	return ok && restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,317 @@
+package utils
+
+import (
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lib/pq"
+)
+
+// VoiceConfig holds a guild's rewards for time spent in voice
+type VoiceConfig struct {
+	XPPerMinute    int64 // only awarded while chat XP is enabled
+	CoinsPerMinute int64
+}
+
+// LoadVoiceConfig returns the guild's voice rewards, which are off by default
+func LoadVoiceConfig(q Querier, guildID string) (*VoiceConfig, error) {
+	config := &VoiceConfig{}
+	err := q.QueryRow(`
+		SELECT COALESCE(voice_xp_per_minute, 0), COALESCE(voice_coins_per_minute, 0)
+		FROM guilds
+		WHERE guild_id = $1
+	`, guildID).Scan(&config.XPPerMinute, &config.CoinsPerMinute)
+	if err == sql.ErrNoRows {
+		return config, nil
+	}
+	return config, err
+}
+
+// voiceSession is a member's stay in one voice channel. Time only counts while
+// the session is active: outside the AFK channel, undeafened and with at least
+// one other member in the channel.
+type voiceSession struct {
+	id          int64
+	guildID     string
+	userID      string
+	channelID   string
+	activeSince time.Time // zero while inactive
+	pending     int64     // active seconds not yet written
+}
+
+// VoiceService records voice sessions from voice state updates and pays voice
+// rewards for active time. Sessions are rebuilt from the gateway's voice states,
+// so events arriving out of order or while disconnected can't skew the totals.
+type VoiceService struct {
+	db      *sql.DB
+	session *discordgo.Session
+	levels  *LevelService
+
+	mu       sync.Mutex
+	sessions map[string]*voiceSession // keyed by guildID:userID
+	closing  []*voiceSession          // ended sessions whose last write failed
+}
+
+func NewVoiceService(db *sql.DB, session *discordgo.Session, levels *LevelService) *VoiceService {
+	return &VoiceService{db: db, session: session, levels: levels, sessions: make(map[string]*voiceSession)}
+}
+
+// Start writes active time and pays rewards every minute
+func (vs *VoiceService) Start() {
+	log.Println("Starting voice service...")
+
+	ticker := time.NewTicker(1 * time.Minute) // Flush every minute
+	defer ticker.Stop()
+
+	for range ticker.C {
+		vs.flush()
+	}
+}
+
+// HandleVoiceStateUpdate is the VoiceStateUpdate handler
+func (vs *VoiceService) HandleVoiceStateUpdate(s *discordgo.Session, v *discordgo.VoiceStateUpdate) {
+	vs.reconcile(v.GuildID)
+}
+
+// HandleGuildCreate picks up members already in voice when the bot connects. Sessions
+// left open by a previous run are closed at the last time they were written.
+func (vs *VoiceService) HandleGuildCreate(s *discordgo.Session, g *discordgo.GuildCreate) {
+	vs.mu.Lock()
+	var tracked []int64
+	for _, session := range vs.sessions {
+		if session.guildID == g.ID {
+			tracked = append(tracked, session.id)
+		}
+	}
+	for _, session := range vs.closing {
+		if session.guildID == g.ID {
+			tracked = append(tracked, session.id)
+		}
+	}
+	vs.mu.Unlock()
+
+	_, err := vs.db.Exec(`
+		UPDATE voice_sessions SET left_at = updated_at
+		WHERE guild_id = $1 AND left_at IS NULL AND NOT (session_id = ANY($2))
+	`, g.ID, pq.Array(tracked))
+	if err != nil {
+		log.Printf("Error closing stale voice sessions in guild %s: %v", g.ID, err)
+	}
+
+	vs.reconcile(g.ID)
+}
+
+// reconcile brings the guild's sessions in line with its current voice states
+func (vs *VoiceService) reconcile(guildID string) {
+	guild, err := vs.session.State.Guild(guildID)
+	if err != nil {
+		return
+	}
+
+	// Copy the voice states so the state lock isn't held during database writes
+	vs.session.State.RLock()
+	afkChannelID := guild.AfkChannelID
+	voiceStates := make([]discordgo.VoiceState, 0, len(guild.VoiceStates))
+	for _, state := range guild.VoiceStates {
+		voiceStates = append(voiceStates, *state)
+	}
+	vs.session.State.RUnlock()
+
+	type memberState struct {
+		channelID string
+		deafened  bool
+	}
+	states := make(map[string]memberState)
+	humans := make(map[string]int)
+	for _, state := range voiceStates {
+		if state.ChannelID == "" || vs.isBot(guildID, &state) {
+			continue
+		}
+		states[state.UserID] = memberState{state.ChannelID, state.Deaf || state.SelfDeaf}
+		humans[state.ChannelID]++
+	}
+
+	now := time.Now()
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	for key, session := range vs.sessions {
+		if session.guildID != guildID {
+			continue
+		}
+		if state, ok := states[session.userID]; !ok || state.channelID != session.channelID {
+			session.deactivate(now)
+			if err := vs.write(session, true); err != nil {
+				// The member may rejoin before the retry, so it can't stay under key
+				vs.closing = append(vs.closing, session)
+			}
+			delete(vs.sessions, key)
+		}
+	}
+
+	for userID, state := range states {
+		key := guildID + ":" + userID
+		session, ok := vs.sessions[key]
+		if !ok {
+			session = &voiceSession{guildID: guildID, userID: userID, channelID: state.channelID}
+			if err := vs.open(session); err != nil {
+				log.Printf("Error opening voice session for %s in guild %s: %v", userID, guildID, err)
+				continue
+			}
+			vs.sessions[key] = session
+		}
+
+		active := state.channelID != afkChannelID && !state.deafened && humans[state.channelID] >= 2
+		if !active {
+			session.deactivate(now)
+		} else if session.activeSince.IsZero() {
+			session.activeSince = now
+		}
+	}
+}
+
+// isBot reports whether a voice state belongs to a bot
+func (vs *VoiceService) isBot(guildID string, state *discordgo.VoiceState) bool {
+	if state.Member != nil && state.Member.User != nil {
+		return state.Member.User.Bot
+	}
+	if member, err := vs.session.State.Member(guildID, state.UserID); err == nil && member.User != nil {
+		return member.User.Bot
+	}
+	return false
+}
+
+// deactivate moves the active time so far into pending
+func (s *voiceSession) deactivate(now time.Time) {
+	if s.activeSince.IsZero() {
+		return
+	}
+	s.pending += int64(now.Sub(s.activeSince).Seconds())
+	s.activeSince = time.Time{}
+}
+
+func (vs *VoiceService) open(session *voiceSession) error {
+	tx, err := vs.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO voice_sessions (guild_id, user_id, channel_id)
+		VALUES ($1, $2, $3)
+		RETURNING session_id
+	`, session.guildID, session.userID, session.channelID).Scan(&session.id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO voice_stats (guild_id, user_id, sessions)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (guild_id, user_id) DO UPDATE SET sessions = voice_stats.sessions + 1
+	`, session.guildID, session.userID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// flush writes the active time of every open session so far and retries closing
+// the sessions that failed to close
+func (vs *VoiceService) flush() {
+	now := time.Now()
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	failed := vs.closing[:0]
+	for _, session := range vs.closing {
+		if err := vs.write(session, true); err != nil {
+			failed = append(failed, session)
+		}
+	}
+	vs.closing = failed
+
+	for _, session := range vs.sessions {
+		if !session.activeSince.IsZero() {
+			session.pending += int64(now.Sub(session.activeSince).Seconds())
+			session.activeSince = now
+		}
+		vs.write(session, false)
+	}
+}
+
+// write adds the session's pending active time to its row and the member's totals and
+// pays rewards for every full minute, closing the session if it ended. Must be called
+// with vs.mu held.
+func (vs *VoiceService) write(session *voiceSession, closing bool) error {
+	xp, err := vs.writeSession(session, closing)
+	if err != nil {
+		// The time stays pending and is written with the next flush
+		log.Printf("Error writing voice session %d: %v", session.id, err)
+		return err
+	}
+	session.pending = 0
+
+	if xp > 0 && vs.levels != nil {
+		// Level ups talk to Discord, so don't hold up other sessions
+		go vs.levels.AddXP(session.guildID, session.userID, session.channelID, xp)
+	}
+	return nil
+}
+
+// writeSession records the pending time and returns the XP earned with it
+func (vs *VoiceService) writeSession(session *voiceSession, closing bool) (int64, error) {
+	tx, err := vs.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE voice_sessions
+		SET active_seconds = active_seconds + $1, updated_at = NOW(),
+			left_at = CASE WHEN $2 THEN NOW() END
+		WHERE session_id = $3
+	`, session.pending, closing, session.id)
+	if err != nil {
+		return 0, err
+	}
+	if session.pending == 0 {
+		return 0, tx.Commit()
+	}
+
+	// Rewards are paid for each full minute the total passes, so partial minutes carry over
+	var minutes int64
+	err = tx.QueryRow(`
+		UPDATE voice_stats
+		SET seconds = seconds + $3
+		WHERE guild_id = $1 AND user_id = $2
+		RETURNING seconds / 60 - (seconds - $3) / 60
+	`, session.guildID, session.userID, session.pending).Scan(&minutes)
+	if err != nil {
+		return 0, err
+	}
+
+	config, err := LoadVoiceConfig(tx, session.guildID)
+	if err != nil {
+		return 0, err
+	}
+	if minutes > 0 && config.CoinsPerMinute > 0 {
+		if _, err := LockBalance(tx, session.guildID, session.userID); err != nil {
+			return 0, err
+		}
+		_, err = ApplyChange(tx, BalanceChange{
+			GuildID: session.guildID, UserID: session.userID, Amount: minutes * config.CoinsPerMinute,
+			Reason: ReasonVoice,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return minutes * config.XPPerMinute, tx.Commit()
+}